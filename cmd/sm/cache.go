@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/inspirai-store/inspirai-devkit/internal/submodule"
+	"github.com/spf13/cobra"
+)
+
+func cacheCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the local mirror cache used to speed up clones",
+		Long: `Manage bare mirrors kept in cache_dir, one per repo URL
+(e.g. ~/.cache/sm/mirrors/github.com/org/repo.git).
+
+When cache_dir is set, sm init clones with --reference-if-able <mirror>
+--dissociate, so only objects missing from the mirror are downloaded and the
+new checkout does not depend on the cache afterwards. A mirror is only used
+when its origin is the submodule's repo, so forks never borrow each other's
+mirror.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "update",
+		Short: "Create or refresh the mirror of every submodule",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			reporter := newReporter(1)
+			result, err := submodule.CacheUpdate(cfg, reporter)
+			closeReporter(reporter)
+			if err != nil {
+				return err
+			}
+			return renderResults(result)
+		},
+	})
+
+	var (
+		dryRunFlag     bool
+		unusedDaysFlag int
+	)
+	prune := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove mirrors that are known to be stale",
+		Long: `Remove mirrors that sm can prove are stale.
+
+The cache may be shared by several workspaces, so a mirror is never removed
+just because this workspace does not configure its repo. Removed are mirrors
+that are not at the location of their origin URL (sm never reads them) and,
+with --unused-days, mirrors that sm has not used for that many days. Mirrors
+of the current submodules and anything that is not a mirror are left alone.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			pruned, err := submodule.CachePrune(cfg, submodule.PruneOptions{
+				DryRun:    dryRunFlag,
+				UnusedFor: time.Duration(unusedDaysFlag) * 24 * time.Hour,
+			})
+			if err != nil {
+				return err
+			}
+			return render(pruned, func() {
+				for _, m := range pruned {
+					if dryRunFlag {
+						color.Yellow("  [would prune] %s (%s)", m.Path, m.Reason)
+					} else {
+						color.Yellow("  [prune] %s (%s)", m.Path, m.Reason)
+					}
+				}
+				fmt.Printf("%d stale mirrors\n", len(pruned))
+			})
+		},
+	}
+	prune.Flags().BoolVar(&dryRunFlag, "dry-run", false, "Only show which mirrors would be removed")
+	prune.Flags().IntVar(&unusedDaysFlag, "unused-days", 0, "Also remove mirrors that sm has not used for this many days")
+	cmd.AddCommand(prune)
+
+	return cmd
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fatih/color"
+	"github.com/inspirai-store/inspirai-devkit/internal/config"
+	"github.com/inspirai-store/inspirai-devkit/internal/submodule"
+	"github.com/spf13/cobra"
+)
+
+func lockCmd() *cobra.Command {
+	var checkFlag bool
+
+	cmd := &cobra.Command{
+		Use:   "lock",
+		Short: "Record the current commit of every submodule in " + config.LockFile,
+		Long: `Write ` + config.LockFile + ` with the commit SHA, branch and remote URL of every
+cloned submodule, so the exact set of commits can be restored later with
+sm init --locked or sm checkout --locked. Entries of repos that are not cloned
+are kept from the existing ` + config.LockFile + `.
+
+With --check nothing is written; instead every submodule is compared with
+` + config.LockFile + ` and the command exits non-zero if any of them is ahead,
+behind, diverged, missing or not locked. Repos outside the saved selection or
+profile, and repos that are neither cloned nor locked, are not drift.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			if checkFlag {
+				lock, err := config.LoadLock(root)
+				if err != nil {
+					return err
+				}
+				// 只比较当前选择中的仓库，部分 clone 的工作区也能通过检查
+				selected, err := applySelection(root, cfg, &selectionFlags{}, false)
+				if err != nil {
+					return err
+				}
+				report := submodule.CheckLock(cfg, root, lock, unselected(cfg, selected))
+				if err := render(report, report.Print); err != nil {
+					return err
+				}
+				return report.Err()
+			}
+
+			var previous *config.Lock
+			if _, err := os.Stat(filepath.Join(root, config.LockFile)); err == nil {
+				if previous, err = config.LoadLock(root); err != nil {
+					return err
+				}
+			}
+			reporter := newReporter(1)
+			lock, err := submodule.Lock(cfg, root, previous, reporter)
+			closeReporter(reporter)
+			if err != nil {
+				return err
+			}
+			if err := config.SaveLock(root, lock); err != nil {
+				return err
+			}
+
+			return render(lock, func() {
+				color.Green("Wrote %s (%d submodules)", config.LockFile, len(lock.Submodules))
+			})
+		},
+	}
+
+	cmd.Flags().BoolVar(&checkFlag, "check", false, "Verify that every submodule matches "+config.LockFile+" instead of writing it")
+
+	return cmd
+}
+
+func checkoutCmd() *cobra.Command {
+	var lockedFlag bool
+	var failFastFlag bool
+	var sel selectionFlags
+
+	cmd := &cobra.Command{
+		Use:   "checkout --locked",
+		Short: "Restore submodules to the commits recorded in " + config.LockFile,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !lockedFlag {
+				return fmt.Errorf("nothing to check out: use sm checkout --locked")
+			}
+
+			root, cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			cfg, err = applySelection(root, cfg, &sel, false)
+			if err != nil {
+				return err
+			}
+
+			lock, err := config.LoadLock(root)
+			if err != nil {
+				return err
+			}
+
+			reporter := newReporter(1)
+			result := submodule.CheckoutLocked(cfg, root, lock, failFastFlag, reporter)
+			closeReporter(reporter)
+			return renderResults(result)
+		},
+	}
+
+	cmd.Flags().BoolVar(&lockedFlag, "locked", false, "Check out the commits recorded in "+config.LockFile)
+	addFailureFlags(cmd, &failFastFlag)
+	addSelectionFlags(cmd, &sel)
+
+	return cmd
+}
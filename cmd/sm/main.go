@@ -4,28 +4,60 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 
+	"github.com/fatih/color"
 	"github.com/inspirai-store/inspirai-devkit/internal/codegen"
 	"github.com/inspirai-store/inspirai-devkit/internal/config"
 	"github.com/inspirai-store/inspirai-devkit/internal/submodule"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 var version = "0.1.0"
 
+// 全局参数，优先级高于所有配置文件和环境变量
+var (
+	flagSubmodulesDir string
+	flagCloneMethod   string
+	flagCacheDir      string
+)
+
 func main() {
 	rootCmd := &cobra.Command{
 		Use:     "sm",
 		Short:   "Submodule Manager for inspirai projects",
 		Version: version,
+		// 错误由 main 统一输出
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return setupOutput()
+		},
 	}
 
+	rootCmd.PersistentFlags().StringVar(&flagSubmodulesDir, "submodules-dir", "", "Override the submodules directory")
+	rootCmd.PersistentFlags().StringVar(&flagCloneMethod, "clone-method", "", "Override the git clone method (ssh, https)")
+	rootCmd.PersistentFlags().StringVar(&flagCacheDir, "cache-dir", "", "Override the local mirror cache directory")
+	rootCmd.PersistentFlags().StringVar(&flagOutput, "output", outputTable, "Output format (table, json, yaml)")
+	rootCmd.PersistentFlags().StringVar(&flagProgress, "progress", progressAuto, "Progress display (auto, json for JSON lines, none)")
+
 	rootCmd.AddCommand(initCmd())
 	rootCmd.AddCommand(syncCmd())
 	rootCmd.AddCommand(statusCmd())
 	rootCmd.AddCommand(linksCmd())
+	rootCmd.AddCommand(addCmd())
+	rootCmd.AddCommand(removeCmd())
+	rootCmd.AddCommand(unshallowCmd())
 	rootCmd.AddCommand(runCmd())
 	rootCmd.AddCommand(codegenCmd())
+	rootCmd.AddCommand(configCmd())
+	rootCmd.AddCommand(profileCmd())
+	rootCmd.AddCommand(cacheCmd())
+	rootCmd.AddCommand(lockCmd())
+	rootCmd.AddCommand(checkoutCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -33,75 +65,384 @@ func main() {
 	}
 }
 
+// loadConfig 定位项目根目录并加载配置，所有命令共用
+func loadConfig() (string, *config.Config, error) {
+	root, res, err := resolveConfig(false)
+	if err != nil {
+		return "", nil, err
+	}
+	return root, res.Config, nil
+}
+
+// resolveConfig 与 loadConfig 相同，但额外返回每个配置值的来源；
+// skipValidation 为 true 时配置有错误也会返回合并结果
+func resolveConfig(skipValidation bool) (string, *config.Resolved, error) {
+	root, err := config.GetProjectRoot()
+	if err != nil {
+		return "", nil, fmt.Errorf("not in a git repository: %w", err)
+	}
+
+	overrides := map[string]string{}
+	if flagSubmodulesDir != "" {
+		overrides["submodules_dir"] = flagSubmodulesDir
+	}
+	if flagCloneMethod != "" {
+		overrides["clone_method"] = flagCloneMethod
+	}
+	if flagCacheDir != "" {
+		overrides["cache_dir"] = flagCacheDir
+	}
+
+	res, err := config.Resolve(root, config.Options{
+		Overrides:      overrides,
+		SkipValidation: skipValidation,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return root, res, nil
+}
+
+// addFailureFlags 为批量命令注册 --keep-going / --fail-fast，--keep-going=false 等价于 --fail-fast
+func addFailureFlags(cmd *cobra.Command, failFast *bool) {
+	keepGoing := cmd.Flags().VarPF(keepGoingValue{failFast}, "keep-going", "", "Continue with the remaining submodules after a failure")
+	keepGoing.NoOptDefVal = "true"
+	cmd.Flags().BoolVar(failFast, "fail-fast", false, "Stop at the first failing submodule")
+	cmd.MarkFlagsMutuallyExclusive("keep-going", "fail-fast")
+}
+
+// keepGoingValue 是 --keep-going 的取值，保存为 failFast 的相反值
+type keepGoingValue struct {
+	failFast *bool
+}
+
+func (v keepGoingValue) String() string {
+	if v.failFast == nil {
+		return "true"
+	}
+	return strconv.FormatBool(!*v.failFast)
+}
+
+func (v keepGoingValue) Set(s string) error {
+	keepGoing, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*v.failFast = !keepGoing
+	return nil
+}
+
+func (v keepGoingValue) Type() string {
+	return "bool"
+}
+
 func initCmd() *cobra.Command {
-	return &cobra.Command{
+	var jobsFlag int
+	var failFastFlag bool
+	var allFlag bool
+	var lockedFlag bool
+	var sel selectionFlags
+
+	cmd := &cobra.Command{
 		Use:   "init",
 		Short: "Initialize all submodules and create symlinks",
+		Long: `Clone submodules and create symlinks.
+
+The selection (--product, --type, --only, --exclude) is saved locally, so later
+sm sync, sm status and sm links only act on the submodules you checked out.
+Run sm init without selection flags to reuse it, or with --all to clear it.
+Named profiles from the manifest can be used with --profile.
+
+Examples:
+  sm init                              # Clone everything (or the saved selection)
+  sm init --profile frontend           # Clone the repos of a manifest profile
+  sm init --product lingbo --type client
+  sm init --exclude magicbook-service,zeni-x-desktop
+  sm init --all                        # Forget the saved selection
+  sm init --locked                     # Reproduce the commits recorded in sm.lock`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			root, err := config.GetProjectRoot()
+			root, full, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			if allFlag {
+				sel = selectionFlags{}
+				if err := clearSelection(root); err != nil {
+					return err
+				}
+			}
+			cfg, err := applySelection(root, full, &sel, true)
+			if err != nil {
+				return err
+			}
+
+			var lock *config.Lock
+			if lockedFlag {
+				if lock, err = config.LoadLock(root); err != nil {
+					return err
+				}
+			}
+
+			notice("Initializing submodules...")
+			reporter := newReporter(jobsFlag)
+			result, err := submodule.Init(cfg, root, submodule.InitOptions{
+				Jobs:      jobsFlag,
+				FailFast:  failFastFlag,
+				Reporter:  reporter,
+				KeepLinks: unselected(full, cfg),
+			})
+			closeReporter(reporter)
 			if err != nil {
-				return fmt.Errorf("not in a git repository: %w", err)
+				return err
+			}
+			if lock == nil || (result.Err() != nil && failFastFlag) {
+				return renderResults(result)
 			}
 
-			cfg := config.DefaultConfig()
-			fmt.Println("Initializing submodules...")
-			return submodule.Init(cfg, root)
+			notice("Restoring commits from %s...", config.LockFile)
+			reporter = newReporter(1)
+			restored := submodule.CheckoutLocked(cfg, root, lock, failFastFlag, reporter)
+			closeReporter(reporter)
+			return renderResults(result, restored)
 		},
 	}
+
+	cmd.Flags().IntVarP(&jobsFlag, "jobs", "j", 1, "Number of repositories to clone in parallel")
+	cmd.Flags().BoolVar(&allFlag, "all", false, "Clone every submodule and clear the saved selection")
+	cmd.Flags().BoolVar(&lockedFlag, "locked", false, "Check out the commits recorded in "+config.LockFile+" after cloning")
+	addFailureFlags(cmd, &failFastFlag)
+	addSelectionFlags(cmd, &sel)
+	cmd.Flags().StringVar(&sel.profile, "profile", "", "Clone the submodules of a manifest profile")
+	for _, name := range []string{"product", "type", "only", "exclude"} {
+		cmd.MarkFlagsMutuallyExclusive("profile", name)
+	}
+	cmd.MarkFlagsMutuallyExclusive("all", "profile")
+	cmd.MarkFlagsMutuallyExclusive("all", "product")
+	cmd.MarkFlagsMutuallyExclusive("all", "type")
+	cmd.MarkFlagsMutuallyExclusive("all", "only")
+	cmd.MarkFlagsMutuallyExclusive("all", "exclude")
+
+	return cmd
 }
 
 func syncCmd() *cobra.Command {
-	return &cobra.Command{
+	var opts submodule.SyncOptions
+	var sel selectionFlags
+
+	cmd := &cobra.Command{
 		Use:   "sync",
-		Short: "Sync all submodules (git pull --rebase)",
+		Short: "Sync all submodules (git pull --rebase by default)",
+		Long: `Sync all submodules with their upstream.
+
+The strategy comes from --strategy, then the submodule's strategy in the
+manifest, and defaults to rebase:
+  rebase   git fetch, then git rebase @{upstream}
+  merge    git fetch, then git merge @{upstream}
+  ff-only  git fetch, then git merge --ff-only @{upstream}
+  fetch    git fetch --prune (never touches the working tree)
+
+A rebase or merge that stops with conflicts is aborted automatically and the
+repo is reported as conflicted.
+
+Repos with uncommitted changes, a detached HEAD or no upstream branch are
+skipped with the reason listed in the summary; --force syncs them anyway.
+Repos with a rebase or merge already in progress are always skipped.
+
+With -j N, N repos are synced at once. On a terminal each repo gets a line
+that is updated in place; otherwise every phase change is printed as a
+"[name] phase" line. The git output of failed repos is printed at the end.
+
+After syncing, every repo that received new commits is listed with the commit
+count, the incoming one-line log (at most 10 entries) and the number of files
+changed. --output json or yaml prints this report instead of the tables.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			root, err := config.GetProjectRoot()
+			if opts.Strategy != "" && !slices.Contains(config.KnownStrategies, opts.Strategy) {
+				return fmt.Errorf("unknown strategy %q (expected one of: %s)", opts.Strategy, strings.Join(config.KnownStrategies, ", "))
+			}
+
+			root, cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			cfg, err = applySelection(root, cfg, &sel, false)
 			if err != nil {
-				return fmt.Errorf("not in a git repository: %w", err)
+				return err
 			}
 
-			cfg := config.DefaultConfig()
-			fmt.Println("Syncing submodules...")
-			return submodule.Sync(cfg, root)
+			notice("Syncing submodules...")
+			opts.Reporter = newReporter(opts.Jobs)
+			report := submodule.Sync(cfg, root, opts)
+			closeReporter(opts.Reporter)
+			if err := render(report.Repos, report.Print); err != nil {
+				return err
+			}
+			return report.Err()
 		},
 	}
+
+	cmd.Flags().IntVarP(&opts.Jobs, "jobs", "j", 1, "Number of repositories to sync in parallel")
+	cmd.Flags().StringVar(&opts.Strategy, "strategy", "", "Override the sync strategy (rebase, merge, ff-only, fetch)")
+	cmd.Flags().BoolVar(&opts.Autostash, "autostash", false, "Stash local changes before pulling and restore them afterwards")
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "Sync repos even if they are dirty, detached or have no upstream")
+	cmd.Flags().BoolVar(&opts.UpdatePins, "update-pins", false, "Check out the tag/revision from the manifest for pinned submodules")
+	addFailureFlags(cmd, &opts.FailFast)
+	addSelectionFlags(cmd, &sel)
+
+	return cmd
 }
 
 func statusCmd() *cobra.Command {
-	return &cobra.Command{
+	var sel selectionFlags
+	var opts submodule.StatusOptions
+	var longFlag bool
+
+	cmd := &cobra.Command{
 		Use:   "status",
 		Short: "Show status of all submodules",
+		Long: `Show the branch, upstream, ahead/behind counts (SYNC, +ahead -behind),
+working tree status, stash count and last commit of every submodule.
+
+STATUS counts changed files per category: =conflicted +staged !modified
+?untracked. It shows rebasing, merging, cherry-picking or reverting when such
+an operation is in progress. Ahead/behind counts are based on the last fetch;
+--fetch fetches every repo first.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			root, err := config.GetProjectRoot()
+			root, cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			cfg, err = applySelection(root, cfg, &sel, false)
 			if err != nil {
-				return fmt.Errorf("not in a git repository: %w", err)
+				return err
 			}
 
-			cfg := config.DefaultConfig()
-			return submodule.Status(cfg, root)
+			opts.Reporter = newReporter(1)
+			statuses, err := submodule.Status(cfg, root, opts)
+			if err != nil {
+				return err
+			}
+			return render(statuses, func() { submodule.PrintStatus(statuses, longFlag) })
 		},
 	}
+
+	cmd.Flags().BoolVar(&opts.Fetch, "fetch", false, "Fetch every repo before computing ahead/behind counts")
+	cmd.Flags().BoolVarP(&longFlag, "long", "l", false, "List the changed files under each repo")
+	cmd.Flags().BoolVarP(&opts.Short, "short", "s", false, "Only show repos whose working tree is not clean")
+	addSelectionFlags(cmd, &sel)
+
+	return cmd
 }
 
 func linksCmd() *cobra.Command {
-	return &cobra.Command{
+	var sel selectionFlags
+	var dryRunFlag, checkFlag bool
+
+	cmd := &cobra.Command{
 		Use:   "links",
-		Short: "Rebuild all symlinks",
+		Short: "Rebuild the symlink views",
+		Long: `Create the symlinks of every view and remove the ones sm created earlier
+that are no longer needed (e.g. after a repo was removed or renamed).
+
+Existing files and directories in the views are never overwritten.
+
+With --check nothing is changed: dangling links (repo not cloned), links with
+the wrong target, missing links and unexpected entries are reported and the
+command exits non-zero if there are any.
+
+Examples:
+  sm links              # Rebuild the views
+  sm links --dry-run    # Show what would be created and removed
+  sm links --check      # Verify the views, e.g. in a pre-flight script`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, full, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			cfg, err := applySelection(root, full, &sel, false)
+			if err != nil {
+				return err
+			}
+
+			if checkFlag {
+				check, err := submodule.CheckLinks(cfg, root, unselected(full, cfg))
+				if err != nil {
+					return err
+				}
+				if err := render(check, check.Print); err != nil {
+					return err
+				}
+				return check.Err()
+			}
+
+			links, err := submodule.CreateLinks(cfg, root, submodule.LinkOptions{
+				DryRun:   dryRunFlag,
+				Keep:     unselected(full, cfg),
+				Reporter: newReporter(1),
+			})
+			if err != nil {
+				return err
+			}
+			if err := render(links, func() { submodule.PrintLinks(root, links, dryRunFlag) }); err != nil {
+				return err
+			}
+			return submodule.LinksErr(links)
+		},
+	}
+
+	addSelectionFlags(cmd, &sel)
+	cmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "Show the planned changes without touching any files")
+	cmd.Flags().BoolVar(&checkFlag, "check", false, "Report dangling, mis-targeted, missing and unexpected links")
+	cmd.MarkFlagsMutuallyExclusive("dry-run", "check")
+
+	return cmd
+}
+
+func unshallowCmd() *cobra.Command {
+	var opts submodule.UnshallowOptions
+
+	cmd := &cobra.Command{
+		Use:   "unshallow <name>",
+		Short: "Fetch the full history of a shallow or single-branch clone",
+		Long: `Deepen a submodule that was cloned with depth, single_branch or sparse options.
+
+Examples:
+  sm unshallow lingbo-desktop                  # Fetch the complete history
+  sm unshallow lingbo-desktop --deepen 100     # Fetch 100 more commits
+  sm unshallow lingbo-desktop --all-branches   # Also fetch every remote branch
+  sm unshallow lingbo-desktop --no-sparse      # Check out all files`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			root, err := config.GetProjectRoot()
+			root, cfg, err := loadConfig()
 			if err != nil {
-				return fmt.Errorf("not in a git repository: %w", err)
+				return err
 			}
 
-			cfg := config.DefaultConfig()
-			return submodule.CreateLinks(cfg, root)
+			opts.Reporter = newReporter(1)
+			result, err := submodule.Unshallow(cfg, root, args[0], opts)
+			closeReporter(opts.Reporter)
+			if err != nil {
+				return err
+			}
+			return renderResults(result)
 		},
 	}
+
+	cmd.Flags().IntVar(&opts.Deepen, "deepen", 0, "Only fetch this many additional commits")
+	cmd.Flags().BoolVar(&opts.AllBranches, "all-branches", false, "Fetch all remote branches, not just the cloned one")
+	cmd.Flags().BoolVar(&opts.NoSparse, "no-sparse", false, "Disable sparse-checkout and check out every file")
+
+	return cmd
 }
 
 func runCmd() *cobra.Command {
 	var listFlag bool
 	var productFlag string
+	var tagFlag, ownerFlag []string
+	var failFastFlag bool
 
 	cmd := &cobra.Command{
 		Use:   "run <project> <command>",
@@ -117,27 +458,48 @@ Examples:
   sm run lingbo-desktop dev     # Run 'just dev' in lingbo-desktop
   sm run lingbo-web dev         # Run 'npm run dev' in lingbo-web
   sm run --product lingbo dev   # Run 'dev' in all lingbo projects
+  sm run --tag go test          # Run 'test' in all projects tagged go
   sm run --list                 # List all projects and their runners`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			root, err := config.GetProjectRoot()
+			root, cfg, err := loadConfig()
 			if err != nil {
-				return fmt.Errorf("not in a git repository: %w", err)
+				return err
 			}
 
-			cfg := config.DefaultConfig()
+			// --product/--tag/--owner 选择一组项目
+			sel := config.Selection{Tags: tagFlag, Owners: ownerFlag}
+			if productFlag != "" {
+				sel.Products = []string{productFlag}
+			}
+			if !sel.IsEmpty() {
+				if err := sel.Check(cfg); err != nil {
+					return err
+				}
+				cfg = cfg.Select(sel)
+			}
 
 			// List mode
 			if listFlag {
-				submodule.ListRunnable(cfg, root)
-				return nil
+				runnables := submodule.ListRunnable(cfg, root)
+				return render(runnables, func() { submodule.PrintRunnable(runnables) })
 			}
 
-			// Product mode
-			if productFlag != "" {
+			// Group mode
+			if !sel.IsEmpty() {
 				if len(args) < 1 {
-					return fmt.Errorf("command required: sm run --product <product> <command>")
+					return fmt.Errorf("command required: sm run --product|--tag|--owner <value> <command>")
+				}
+				if len(cfg.Submodules) == 0 {
+					return fmt.Errorf("no projects match %s", sel)
+				}
+				result, err := submodule.RunAll(cfg, root, args[0], submodule.RunOptions{
+					FailFast: failFastFlag,
+					Reporter: newReporter(1),
+				})
+				if err != nil {
+					return err
 				}
-				return submodule.RunProduct(cfg, root, productFlag, args[0])
+				return renderResults(result)
 			}
 
 			// Project mode
@@ -145,12 +507,15 @@ Examples:
 				return fmt.Errorf("usage: sm run <project> <command>")
 			}
 
-			return submodule.Run(cfg, root, args[0], args[1])
+			return submodule.Run(cfg, root, args[0], args[1], newReporter(1))
 		},
 	}
 
 	cmd.Flags().BoolVarP(&listFlag, "list", "l", false, "List all projects and their runners")
 	cmd.Flags().StringVarP(&productFlag, "product", "p", "", "Run command in all projects of a product")
+	cmd.Flags().StringSliceVar(&tagFlag, "tag", nil, "Run command in all projects with any of these tags")
+	cmd.Flags().StringSliceVar(&ownerFlag, "owner", nil, "Run command in all projects owned by any of these owners")
+	addFailureFlags(cmd, &failFastFlag)
 
 	return cmd
 }
@@ -177,12 +542,12 @@ Examples:
   sm codegen inspirai-user --lang go -o ./gen    # Generate Go code
   sm codegen inspirai-user --lang ts -o ./types  # Generate TypeScript`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			root, err := config.GetProjectRoot()
+			root, cfg, err := loadConfig()
 			if err != nil {
-				return fmt.Errorf("not in a git repository: %w", err)
+				return err
 			}
 
-			specDir := filepath.Join(root, ".submodules", "inspirai-api-specs")
+			specDir := filepath.Join(root, cfg.SubmodulesDir, "inspirai-api-specs")
 
 			// List mode
 			if listFlag {
@@ -190,11 +555,12 @@ Examples:
 				if err != nil {
 					return err
 				}
-				fmt.Println("Available services:")
-				for _, svc := range services {
-					fmt.Printf("  - %s\n", svc)
-				}
-				return nil
+				return render(services, func() {
+					fmt.Println("Available services:")
+					for _, svc := range services {
+						fmt.Printf("  - %s\n", svc)
+					}
+				})
 			}
 
 			if len(args) < 1 {
@@ -216,13 +582,106 @@ Examples:
 			}
 
 			fmt.Printf("Generating %s code for %s...\n", langFlag, args[0])
-			return gen.Generate(args[0])
+			result, err := gen.Generate(args[0])
+			if err != nil {
+				return err
+			}
+			return render(result, result.Print)
 		},
 	}
 
 	cmd.Flags().StringVarP(&langFlag, "lang", "l", "", "Target language (go, typescript)")
-	cmd.Flags().StringVarP(&outputFlag, "output", "o", "", "Output directory")
+	cmd.Flags().StringVarP(&outputFlag, "out-dir", "o", "", "Output directory")
 	cmd.Flags().BoolVar(&listFlag, "list", false, "List available services")
 
 	return cmd
 }
+
+func configCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the workspace configuration",
+	}
+
+	cmd.AddCommand(configShowCmd())
+	cmd.AddCommand(configValidateCmd())
+
+	return cmd
+}
+
+func configShowCmd() *cobra.Command {
+	var resolvedFlag bool
+
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Print the effective configuration",
+		Long: `Print the effective configuration after merging every layer.
+
+Layers are applied in this order, later ones win:
+  built-in < sm.yaml / .sm/config.yaml < sm.local.yaml < SM_* env vars < flags
+
+Examples:
+  sm config show               # Print the merged configuration
+  sm config show --resolved    # Annotate each value with where it came from`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, res, err := resolveConfig(true)
+			if err != nil {
+				return err
+			}
+
+			if flagOutput == outputJSON {
+				if resolvedFlag {
+					return render(map[string]any{"config": res.Config, "origins": res.Origins}, nil)
+				}
+				return render(res.Config, nil)
+			}
+
+			node := res.Node
+			if resolvedFlag {
+				node = res.Annotated()
+			}
+
+			enc := yaml.NewEncoder(stdout)
+			enc.SetIndent(2)
+			defer enc.Close()
+			return enc.Encode(node)
+		},
+	}
+
+	cmd.Flags().BoolVar(&resolvedFlag, "resolved", false, "Show where each value came from")
+
+	return cmd
+}
+
+func configValidateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "Check the configuration for errors",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, res, err := resolveConfig(true)
+			if err != nil {
+				return err
+			}
+
+			problems := res.Validate()
+			if problems == nil {
+				problems = []config.Problem{}
+			}
+			if err := render(problems, func() {
+				if len(problems) == 0 {
+					color.Green("Configuration is valid (%d submodules)", len(res.Config.Submodules))
+				}
+				for _, p := range problems {
+					color.Red("  %s", p)
+				}
+			}); err != nil {
+				return err
+			}
+
+			if len(problems) > 0 {
+				return fmt.Errorf("%d problems found", len(problems))
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/inspirai-store/inspirai-devkit/internal/config"
+	"github.com/inspirai-store/inspirai-devkit/internal/submodule"
+	"github.com/spf13/cobra"
+)
+
+func addCmd() *cobra.Command {
+	var sm config.SubmoduleConfig
+	var noCloneFlag bool
+
+	cmd := &cobra.Command{
+		Use:   "add <repo-url>",
+		Short: "Add a submodule to the manifest, clone it and create its links",
+		Long: `Add a repository to the manifest (sm.yaml), then clone it and create its links.
+
+The name defaults to the last part of the URL without .git. Comments and the
+order of entries in the manifest are kept. Without a manifest, sm.yaml is
+created from the built-in submodule list first.
+
+Examples:
+  sm add git@github.com:inspirai-store/inspirai-billing.git --type service --product inspirai
+  sm add git@github.com:inspirai-store/zeni-x-web.git --type client --product zenix --name zenix-web
+  sm add <url> --type tools --product independent --tag go --no-clone`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			sm.Repo = args[0]
+			if sm.Name == "" {
+				sm.Name = config.RepoName(sm.Repo)
+			}
+			if slices.ContainsFunc(cfg.Submodules, func(s config.SubmoduleConfig) bool { return s.Name == sm.Name }) {
+				return fmt.Errorf("submodule %q already exists", sm.Name)
+			}
+
+			manifest, err := config.AddSubmodule(root, sm)
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(root, manifest)
+			color.Green("Added %s to %s", sm.Name, rel)
+			if noCloneFlag {
+				return render(sm, func() {})
+			}
+
+			// 重新加载，使 sm.local.yaml 等覆盖也作用于新仓库
+			if _, cfg, err = loadConfig(); err != nil {
+				return err
+			}
+			selected := cfg.Select(config.Selection{Only: []string{sm.Name}})
+			reporter := newReporter(1)
+			result, err := submodule.Init(selected, root, submodule.InitOptions{
+				Reporter:  reporter,
+				KeepLinks: unselected(cfg, selected),
+			})
+			closeReporter(reporter)
+			if err != nil {
+				return err
+			}
+			return renderResults(result)
+		},
+	}
+
+	cmd.Flags().StringVar(&sm.Name, "name", "", "Submodule name (default: inferred from the URL)")
+	cmd.Flags().StringVar(&sm.Type, "type", "", "Submodule type: "+strings.Join(config.KnownTypes, ", "))
+	cmd.Flags().StringVar(&sm.Product, "product", "", "Product the submodule belongs to")
+	cmd.Flags().StringVar(&sm.Branch, "branch", "", "Branch to clone and track")
+	cmd.Flags().StringSliceVar(&sm.Tags, "tag", nil, "Tags of the submodule")
+	cmd.Flags().StringSliceVar(&sm.Owners, "owner", nil, "Owners of the submodule")
+	cmd.Flags().BoolVar(&noCloneFlag, "no-clone", false, "Only update the manifest")
+	cmd.MarkFlagRequired("type")
+	cmd.MarkFlagRequired("product")
+
+	return cmd
+}
+
+func removeCmd() *cobra.Command {
+	var archiveFlag, deleteFlag bool
+
+	cmd := &cobra.Command{
+		Use:   "remove <name>",
+		Short: "Remove a submodule from the manifest and delete its links",
+		Long: `Remove a submodule from the manifest (including references in profiles)
+and delete its links.
+
+The checkout is kept unless --archive (move it to <submodules_dir>/.archive/)
+or --delete (only if it is clean and fully pushed) is given. The manifest is
+validated and written first, and restored if the checkout cannot be archived
+or deleted. A submodule that is the only repo of a profile or of the saved
+selection cannot be removed, since that would select every submodule.
+
+Examples:
+  sm remove magicbook-admin
+  sm remove magicbook-admin --delete`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			name := args[0]
+			if !slices.ContainsFunc(cfg.Submodules, func(s config.SubmoduleConfig) bool { return s.Name == name }) {
+				return fmt.Errorf("unknown submodule %q", name)
+			}
+			state, err := config.LoadState(root)
+			if err != nil {
+				return err
+			}
+			if _, err := withoutSubmodule(state.Selection, name); err != nil {
+				return err
+			}
+
+			mode := submodule.RetireKeep
+			switch {
+			case archiveFlag:
+				mode = submodule.RetireArchive
+			case deleteFlag:
+				mode = submodule.RetireRemove
+			}
+
+			// 先修改并校验 manifest，失败时不动仓库；之后处理仓库失败时恢复 manifest
+			original, err := os.ReadFile(config.FindManifest(root))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			manifest, err := config.RemoveSubmodule(root, name)
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(root, manifest)
+			restore := func() error {
+				if err := os.WriteFile(manifest, original, 0644); err != nil {
+					return fmt.Errorf("failed to restore %s: %w", rel, err)
+				}
+				return nil
+			}
+
+			smPath := filepath.Join(root, cfg.SubmodulesDir, name)
+			result := submodule.NewResult("removed")
+			if _, err := os.Stat(smPath); err == nil && mode != submodule.RetireKeep {
+				reporter := newReporter(1)
+				result, err = submodule.Retire(cfg, root, []string{name}, mode, reporter)
+				closeReporter(reporter)
+				if err != nil {
+					return errors.Join(err, restore())
+				}
+				if err := result.Err(); err != nil {
+					if err := restore(); err != nil {
+						return err
+					}
+					return renderResults(result)
+				}
+			} else {
+				reporter := newReporter(1)
+				err := submodule.RemoveLinks(cfg, root, name, reporter)
+				closeReporter(reporter)
+				if err != nil {
+					return errors.Join(err, restore())
+				}
+				result.Done(name)
+			}
+
+			if err := forgetSelected(root, name); err != nil {
+				return err
+			}
+
+			color.Green("Removed %s from %s", name, rel)
+			if mode == submodule.RetireKeep {
+				if _, err := os.Stat(smPath); err == nil {
+					color.Yellow("Checkout kept at %s (use --archive or --delete to remove it)", filepath.Join(cfg.SubmodulesDir, name))
+				}
+			}
+			return renderResults(result)
+		},
+	}
+
+	cmd.Flags().BoolVar(&archiveFlag, "archive", false, "Move the checkout to the archive")
+	cmd.Flags().BoolVar(&deleteFlag, "delete", false, "Delete the checkout (must be clean and pushed)")
+	cmd.MarkFlagsMutuallyExclusive("archive", "delete")
+
+	return cmd
+}
+
+// forgetSelected 从本地保存的选择中删除 submodule，避免之后的命令报告未知的 submodule
+func forgetSelected(root, name string) error {
+	state, err := config.LoadState(root)
+	if err != nil {
+		return err
+	}
+	sel, err := withoutSubmodule(state.Selection, name)
+	if err != nil {
+		return err
+	}
+	if len(sel.Only) == len(state.Selection.Only) && len(sel.Exclude) == len(state.Selection.Exclude) {
+		return nil
+	}
+	state.Selection = sel
+	return config.SaveState(root, state)
+}
+
+// withoutSubmodule 返回去掉 submodule 之后的选择；如果选择只包含它，去掉后会变成
+// "全部 submodule"，此时返回错误
+func withoutSubmodule(sel config.Selection, name string) (config.Selection, error) {
+	only := slices.DeleteFunc(slices.Clone(sel.Only), func(s string) bool { return s == name })
+	if len(sel.Only) > 0 && len(only) == 0 {
+		return sel, fmt.Errorf("the saved selection only contains %q and would select every submodule without it; select other submodules with sm init first", name)
+	}
+	sel.Only = only
+	sel.Exclude = slices.DeleteFunc(slices.Clone(sel.Exclude), func(s string) bool { return s == name })
+	return sel, nil
+}
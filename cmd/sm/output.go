@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/inspirai-store/inspirai-devkit/internal/submodule"
+	"github.com/mattn/go-isatty"
+	"gopkg.in/yaml.v3"
+)
+
+// --output 支持的格式
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+var outputFormats = []string{outputTable, outputJSON, outputYAML}
+
+// flagOutput 是全局 --output 参数
+var flagOutput = outputTable
+
+// --progress 支持的方式
+const (
+	progressAuto = "auto"
+	progressJSON = "json"
+	progressNone = "none"
+)
+
+var progressModes = []string{progressAuto, progressJSON, progressNone}
+
+// flagProgress 是全局 --progress 参数
+var flagProgress = progressAuto
+
+// stdout 是命令结果的输出位置
+var stdout io.Writer = os.Stdout
+
+// setupOutput 校验 --output；json/yaml 时把进度等提示改写到 stderr，
+// 使 stdout 只包含可以直接解析的结果
+func setupOutput() error {
+	if !slices.Contains(outputFormats, flagOutput) {
+		return fmt.Errorf("unknown output format %q (expected one of: %s)", flagOutput, strings.Join(outputFormats, ", "))
+	}
+	if !slices.Contains(progressModes, flagProgress) {
+		return fmt.Errorf("unknown progress mode %q (expected one of: %s)", flagProgress, strings.Join(progressModes, ", "))
+	}
+	if flagOutput != outputTable && os.Stdout != os.Stderr {
+		stdout = os.Stdout
+		os.Stdout = os.Stderr
+		color.Output = color.Error
+	}
+	return nil
+}
+
+// newReporter 按 --progress 创建进度的展示方式；jobs 大于 1 且输出到终端时
+// 每个仓库占一行原地刷新
+func newReporter(jobs int) submodule.Reporter {
+	switch flagProgress {
+	case progressJSON:
+		return submodule.NewJSONReporter(os.Stdout)
+	case progressNone:
+		return submodule.Silent
+	}
+	live := jobs > 1 && isatty.IsTerminal(os.Stdout.Fd())
+	return submodule.NewTerminalReporter(color.Output, live)
+}
+
+// notice 把 "Initializing submodules..." 这类阶段提示写到 stderr，不混入 stdout 上的
+// JSON 进度和结果；--progress none 时不输出
+func notice(format string, args ...any) {
+	if flagProgress == progressNone {
+		return
+	}
+	fmt.Fprintln(os.Stderr, color.CyanString(format, args...))
+}
+
+// closeReporter 结束进度显示，如打印 live 视图中失败仓库的 git 输出
+func closeReporter(r submodule.Reporter) {
+	if c, ok := r.(io.Closer); ok {
+		c.Close()
+	}
+}
+
+// render 按 --output 输出命令结果：table 时调用 table 打印表格，json/yaml 时编码 v
+func render(v any, table func()) error {
+	switch flagOutput {
+	case outputJSON:
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case outputYAML:
+		enc := yaml.NewEncoder(stdout)
+		enc.SetIndent(2)
+		defer enc.Close()
+		return enc.Encode(v)
+	}
+	table()
+	return nil
+}
+
+// renderResults 输出一个或多个批量操作的汇总结果（多个时 json/yaml 为数组），
+// 有 submodule 失败时返回对应的错误
+func renderResults(results ...*submodule.Result) error {
+	var v any = results
+	if len(results) == 1 {
+		v = results[0]
+	}
+
+	var errs []error
+	if err := render(v, func() {
+		for _, r := range results {
+			r.PrintSummary()
+		}
+	}); err != nil {
+		errs = append(errs, err)
+	}
+	for _, r := range results {
+		errs = append(errs, r.Err())
+	}
+	return errors.Join(errs...)
+}
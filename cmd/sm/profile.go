@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/inspirai-store/inspirai-devkit/internal/config"
+	"github.com/inspirai-store/inspirai-devkit/internal/submodule"
+	"github.com/spf13/cobra"
+)
+
+func profileCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Manage workspace profiles defined in the manifest",
+	}
+
+	cmd.AddCommand(profileListCmd())
+	cmd.AddCommand(profileSwitchCmd())
+
+	return cmd
+}
+
+// profileRow 是 sm profile list 输出的一行
+type profileRow struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Repos       int    `json:"repos" yaml:"repos"` // profile 包含的仓库数
+	Active      bool   `json:"active" yaml:"active"`
+}
+
+func profileListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List profiles and mark the active one",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			state, err := config.LoadState(root)
+			if err != nil {
+				return err
+			}
+
+			rows := []profileRow{}
+			for i := range cfg.Profiles {
+				p := &cfg.Profiles[i]
+				rows = append(rows, profileRow{
+					Name:        p.Name,
+					Description: p.Description,
+					Repos:       len(cfg.SelectProfile(p).Submodules),
+					Active:      p.Name == state.Profile,
+				})
+			}
+
+			return render(rows, func() {
+				if len(rows) == 0 {
+					fmt.Println("No profiles defined in the manifest")
+					return
+				}
+
+				fmt.Printf("  %-20s %-6s %s\n", "PROFILE", "REPOS", "DESCRIPTION")
+				fmt.Println(strings.Repeat("-", 60))
+				for _, row := range rows {
+					line := fmt.Sprintf("%-20s %-6d %s", row.Name, row.Repos, row.Description)
+					if row.Active {
+						color.Green("* %s", line)
+					} else {
+						fmt.Printf("  %s\n", line)
+					}
+				}
+			})
+		},
+	}
+}
+
+func profileSwitchCmd() *cobra.Command {
+	var archiveFlag bool
+	var removeFlag bool
+	var jobsFlag int
+
+	cmd := &cobra.Command{
+		Use:   "switch <name>",
+		Short: "Switch to another profile, cloning newly needed repos",
+		Long: `Switch the workspace to another profile.
+
+Repos that the new profile needs are cloned and linked. Checked-out repos that
+are not part of the new profile are kept by default; use --archive to move them
+to <submodules_dir>/.archive/, or --remove to delete them (only if they are
+clean and fully pushed).
+
+Examples:
+  sm profile switch frontend
+  sm profile switch platform-backend --archive`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			p, err := cfg.Profile(args[0])
+			if err != nil {
+				return err
+			}
+
+			state, err := config.LoadState(root)
+			if err != nil {
+				return err
+			}
+			state.Profile = p.Name
+			state.Selection = config.Selection{}
+			if err := config.SaveState(root, state); err != nil {
+				return err
+			}
+
+			// 已经 clone 但不再属于新 profile 的仓库
+			var leftovers []string
+			for _, sm := range cfg.Submodules {
+				if p.Match(sm) {
+					continue
+				}
+				if _, err := os.Stat(filepath.Join(root, cfg.SubmodulesDir, sm.Name)); err == nil {
+					leftovers = append(leftovers, sm.Name)
+				}
+			}
+
+			mode := submodule.RetireKeep
+			switch {
+			case archiveFlag:
+				mode = submodule.RetireArchive
+			case removeFlag:
+				mode = submodule.RetireRemove
+			}
+
+			notice("Switching to profile %s...", p.Name)
+			reporter := newReporter(jobsFlag)
+			selected := cfg.SelectProfile(p)
+			cloned, err := submodule.Init(selected, root, submodule.InitOptions{
+				Jobs:      jobsFlag,
+				Reporter:  reporter,
+				KeepLinks: unselected(cfg, selected),
+			})
+			closeReporter(reporter)
+			if err != nil {
+				return err
+			}
+			if len(leftovers) == 0 {
+				return renderResults(cloned)
+			}
+			reporter = newReporter(1)
+			retired, err := submodule.Retire(cfg, root, leftovers, mode, reporter)
+			closeReporter(reporter)
+			if err != nil {
+				return err
+			}
+			return renderResults(cloned, retired)
+		},
+	}
+
+	cmd.Flags().BoolVar(&archiveFlag, "archive", false, "Move repos that are no longer in the profile to the archive")
+	cmd.Flags().BoolVar(&removeFlag, "remove", false, "Delete repos that are no longer in the profile (must be clean and pushed)")
+	cmd.Flags().IntVarP(&jobsFlag, "jobs", "j", 1, "Number of repositories to clone in parallel")
+	cmd.MarkFlagsMutuallyExclusive("archive", "remove")
+
+	return cmd
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/fatih/color"
+	"github.com/inspirai-store/inspirai-devkit/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// selectionFlags 是 init/sync/status/links 共用的 submodule 过滤参数
+type selectionFlags struct {
+	profile  string // 只有 sm init 注册 --profile
+	products []string
+	types    []string
+	tags     []string
+	owners   []string
+	only     []string
+	exclude  []string
+}
+
+func addSelectionFlags(cmd *cobra.Command, f *selectionFlags) {
+	cmd.Flags().StringSliceVar(&f.products, "product", nil, "Only act on submodules of these products")
+	cmd.Flags().StringSliceVar(&f.types, "type", nil, "Only act on submodules of these types")
+	cmd.Flags().StringSliceVar(&f.tags, "tag", nil, "Only act on submodules with any of these tags")
+	cmd.Flags().StringSliceVar(&f.owners, "owner", nil, "Only act on submodules owned by any of these owners")
+	cmd.Flags().StringSliceVar(&f.only, "only", nil, "Only act on these submodules (comma separated)")
+	cmd.Flags().StringSliceVar(&f.exclude, "exclude", nil, "Skip these submodules (comma separated)")
+}
+
+func (f *selectionFlags) selection() config.Selection {
+	return config.Selection{
+		Products: f.products,
+		Types:    f.types,
+		Tags:     f.tags,
+		Owners:   f.owners,
+		Only:     f.only,
+		Exclude:  f.exclude,
+	}
+}
+
+// applySelection 按命令行参数或本地保存的选择过滤配置
+//
+// 命令行没有指定过滤条件时使用 sm init 保存的 profile 或选择；persist 为 true 时
+// 把本次的选择写入本地状态（只有 sm init 会这样做）。
+func applySelection(root string, cfg *config.Config, f *selectionFlags, persist bool) (*config.Config, error) {
+	state, err := config.LoadState(root)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := f.profile
+	sel := f.selection()
+	if profile == "" && sel.IsEmpty() {
+		profile = state.Profile
+		sel = state.Selection
+		// 提示写到 stderr，不混入 --json 等机器可读的输出
+		switch {
+		case profile != "":
+			fmt.Fprintln(os.Stderr, color.YellowString("Using profile: %s", profile))
+		case !sel.IsEmpty():
+			fmt.Fprintln(os.Stderr, color.YellowString("Using saved selection: %s", sel))
+		}
+	}
+
+	if profile != "" {
+		p, err := cfg.Profile(profile)
+		if err != nil {
+			return nil, err
+		}
+		if persist {
+			state.Profile = profile
+			state.Selection = config.Selection{}
+			if err := config.SaveState(root, state); err != nil {
+				return nil, err
+			}
+		}
+		return cfg.SelectProfile(p), nil
+	}
+
+	if err := sel.Check(cfg); err != nil {
+		return nil, err
+	}
+
+	if persist {
+		state.Profile = ""
+		state.Selection = sel
+		if err := config.SaveState(root, state); err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg.Select(sel), nil
+}
+
+// clearSelection 清除本地保存的 profile 和选择
+func clearSelection(root string) error {
+	state, err := config.LoadState(root)
+	if err != nil {
+		return err
+	}
+	state.Profile = ""
+	state.Selection = config.Selection{}
+	return config.SaveState(root, state)
+}
+
+// unselected 返回 cfg 中不在 selected 里的 submodule，它们的软链不应被当作过期清理
+func unselected(cfg, selected *config.Config) []string {
+	var names []string
+	for _, sm := range cfg.Submodules {
+		if !slices.ContainsFunc(selected.Submodules, func(s config.SubmoduleConfig) bool { return s.Name == sm.Name }) {
+			names = append(names, sm.Name)
+		}
+	}
+	return names
+}
@@ -64,33 +64,67 @@ type Generator struct {
 	Output  string
 }
 
+// Result 记录一次代码生成的结果
+type Result struct {
+	Service string   `json:"service" yaml:"service"`
+	Lang    string   `json:"lang" yaml:"lang"`
+	Specs   int      `json:"specs" yaml:"specs"` // 找到的 API 规范数
+	Files   []string `json:"files" yaml:"files"` // 生成的文件
+	// Skipped 是无法解析而被跳过的规范文件及原因
+	Skipped []SkippedSpec `json:"skipped,omitempty" yaml:"skipped,omitempty"`
+}
+
+// SkippedSpec 是一个无法解析的规范文件
+type SkippedSpec struct {
+	Path  string `json:"path" yaml:"path"`
+	Error string `json:"error" yaml:"error"`
+}
+
+// Print 打印生成结果
+func (r *Result) Print() {
+	for _, skipped := range r.Skipped {
+		color.Yellow("  [skip] %s: %s", skipped.Path, skipped.Error)
+	}
+	color.Cyan("Found %d API specs in %s", r.Specs, r.Service)
+	for _, file := range r.Files {
+		color.Green("  [generated] %s", file)
+	}
+}
+
 // Generate 生成代码
-func (g *Generator) Generate(service string) error {
+func (g *Generator) Generate(service string) (*Result, error) {
 	specPath := filepath.Join(g.SpecDir, service)
 	if _, err := os.Stat(specPath); os.IsNotExist(err) {
-		return fmt.Errorf("service spec not found: %s", specPath)
+		return nil, fmt.Errorf("service spec not found: %s", specPath)
 	}
 
+	result := &Result{Service: service, Lang: g.Lang, Files: []string{}}
+
 	// 收集所有 API 规范
-	specs, err := g.collectSpecs(specPath)
+	specs, err := g.collectSpecs(specPath, result)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	color.Cyan("Found %d API specs in %s", len(specs), service)
+	result.Specs = len(specs)
 
 	// 根据语言生成代码
+	var file string
 	switch g.Lang {
 	case "go":
-		return g.generateGo(service, specs)
+		file, err = g.generateGo(service, specs)
 	case "typescript", "ts":
-		return g.generateTypeScript(service, specs)
+		file, err = g.generateTypeScript(service, specs)
 	default:
-		return fmt.Errorf("unsupported language: %s (supported: go, typescript)", g.Lang)
+		return nil, fmt.Errorf("unsupported language: %s (supported: go, typescript)", g.Lang)
+	}
+	if err != nil {
+		return nil, err
 	}
+	result.Files = append(result.Files, file)
+	return result, nil
 }
 
-func (g *Generator) collectSpecs(dir string) (map[string]*APISpec, error) {
+func (g *Generator) collectSpecs(dir string, result *Result) (map[string]*APISpec, error) {
 	specs := make(map[string]*APISpec)
 
 	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
@@ -108,7 +142,7 @@ func (g *Generator) collectSpecs(dir string) (map[string]*APISpec, error) {
 
 		var spec APISpec
 		if err := yaml.Unmarshal(data, &spec); err != nil {
-			color.Yellow("  [skip] %s: %v", path, err)
+			result.Skipped = append(result.Skipped, SkippedSpec{Path: path, Error: err.Error()})
 			return nil
 		}
 
@@ -128,16 +162,16 @@ func (g *Generator) collectSpecs(dir string) (map[string]*APISpec, error) {
 	return specs, err
 }
 
-func (g *Generator) generateGo(service string, specs map[string]*APISpec) error {
+func (g *Generator) generateGo(service string, specs map[string]*APISpec) (string, error) {
 	if err := os.MkdirAll(g.Output, 0755); err != nil {
-		return err
+		return "", err
 	}
 
 	// 生成类型定义
 	typesFile := filepath.Join(g.Output, "types.go")
 	f, err := os.Create(typesFile)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer f.Close()
 
@@ -169,8 +203,7 @@ func (g *Generator) generateGo(service string, specs map[string]*APISpec) error
 		}
 	}
 
-	color.Green("  [generated] %s", typesFile)
-	return nil
+	return typesFile, nil
 }
 
 func (g *Generator) writeGoField(f *os.File, field Field, indent string) {
@@ -216,16 +249,16 @@ func (g *Generator) toGoType(t string) string {
 	}
 }
 
-func (g *Generator) generateTypeScript(service string, specs map[string]*APISpec) error {
+func (g *Generator) generateTypeScript(service string, specs map[string]*APISpec) (string, error) {
 	if err := os.MkdirAll(g.Output, 0755); err != nil {
-		return err
+		return "", err
 	}
 
 	// 生成类型定义
 	typesFile := filepath.Join(g.Output, "types.ts")
 	f, err := os.Create(typesFile)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer f.Close()
 
@@ -255,8 +288,7 @@ func (g *Generator) generateTypeScript(service string, specs map[string]*APISpec
 		}
 	}
 
-	color.Green("  [generated] %s", typesFile)
-	return nil
+	return typesFile, nil
 }
 
 func (g *Generator) writeTSField(f *os.File, field Field, indent string) {
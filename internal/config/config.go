@@ -9,22 +9,65 @@ import (
 
 // SubmoduleConfig 定义单个 submodule 的配置
 type SubmoduleConfig struct {
-	Name    string `json:"name"`
-	Repo    string `json:"repo"`
-	Type    string `json:"type"`    // service, client, specs, tools
-	Product string `json:"product"` // lingbo, inspirai, independent
+	Name    string `json:"name" yaml:"name"`
+	Repo    string `json:"repo" yaml:"repo"`
+	Type    string `json:"type" yaml:"type"`       // service, client, specs, tools
+	Product string `json:"product" yaml:"product"` // lingbo, inspirai, independent
+
+	// Tags 和 Owners 是自由填写的标签和负责人，如 tags: [go, deployable]，
+	// 可以用于选择 submodule 和软链视图的分组
+	Tags   []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Owners []string `json:"owners,omitempty" yaml:"owners,omitempty"`
+
+	// Alias 是软链视图中的短名称，未设置时去掉名称中的 "<product>-" 前缀
+	Alias string `json:"alias,omitempty" yaml:"alias,omitempty"`
+
+	// Disabled 为 true 时所有命令都会忽略该 submodule（通常写在 sm.local.yaml 中）
+	Disabled bool `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+
+	// clone 选项，用于历史或资源文件很大的仓库
+	Depth        int      `json:"depth,omitempty" yaml:"depth,omitempty"`                 // 浅克隆的提交数
+	Filter       string   `json:"filter,omitempty" yaml:"filter,omitempty"`               // 部分克隆，如 blob:none、tree:0
+	Sparse       []string `json:"sparse,omitempty" yaml:"sparse,omitempty"`               // 只检出这些目录
+	SingleBranch bool     `json:"single_branch,omitempty" yaml:"single_branch,omitempty"` // 只获取默认分支
+
+	// 检出的引用：Branch 跟踪分支；Tag 和 Revision 会把仓库固定在某个提交（detached HEAD）
+	Branch   string `json:"branch,omitempty" yaml:"branch,omitempty"`
+	Tag      string `json:"tag,omitempty" yaml:"tag,omitempty"`
+	Revision string `json:"revision,omitempty" yaml:"revision,omitempty"`
+
+	// Strategy 是 sm sync 的默认同步方式：rebase、merge、ff-only 或 fetch
+	Strategy string `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+}
+
+// Pin 返回 submodule 被固定到的 tag 或 revision，未固定时返回空字符串
+func (sm SubmoduleConfig) Pin() string {
+	if sm.Revision != "" {
+		return sm.Revision
+	}
+	return sm.Tag
 }
 
 // Config 定义 sm 工具的配置
 type Config struct {
-	SubmodulesDir string            `json:"submodules_dir"`
-	Submodules    []SubmoduleConfig `json:"submodules"`
+	SubmodulesDir string            `json:"submodules_dir" yaml:"submodules_dir"`
+	CloneMethod   string            `json:"clone_method" yaml:"clone_method"`               // ssh, https
+	CacheDir      string            `json:"cache_dir,omitempty" yaml:"cache_dir,omitempty"` // 本地 mirror 缓存目录，为空时不使用
+	Products      []string          `json:"products" yaml:"products,omitempty"`
+	Submodules    []SubmoduleConfig `json:"submodules" yaml:"submodules,omitempty"`
+	Profiles      []Profile         `json:"profiles,omitempty" yaml:"profiles,omitempty"`
+	Views         []View            `json:"views,omitempty" yaml:"views,omitempty"` // 为空时使用 DefaultViews
+
+	// Disabled 是被禁用（disabled: true）的 submodule 名称，它们不在 Submodules 中
+	Disabled []string `json:"-" yaml:"-"`
 }
 
 // DefaultConfig 返回默认配置
 func DefaultConfig() *Config {
 	return &Config{
 		SubmodulesDir: ".submodules",
+		CloneMethod:   "ssh",
+		Products:      []string{"lingbo", "inspirai", "magicbook", "zenix", "independent"},
 		Submodules: []SubmoduleConfig{
 			// lingbo 产品线
 			{Name: "lingbo-desktop", Repo: "git@github.com:inspirai-store/lingbo-desktop.git", Type: "client", Product: "lingbo"},
@@ -42,7 +85,7 @@ func DefaultConfig() *Config {
 			{Name: "magicbook-h5", Repo: "git@github.com:inspirai-store/magicbook-h5.git", Type: "client", Product: "magicbook"},
 			{Name: "magicbook-admin", Repo: "git@github.com:inspirai-store/magicbook-admin.git", Type: "client", Product: "magicbook"},
 			// zenix 产品线
-			{Name: "zeni-x-desktop", Repo: "git@github.com:inspirai-store/zeni-x-desktop.git", Type: "tools", Product: "zenix"},
+			{Name: "zeni-x-desktop", Repo: "git@github.com:inspirai-store/zeni-x-desktop.git", Type: "tools", Product: "zenix", Alias: "desktop"},
 			// 独立项目
 			{Name: "skill-market", Repo: "git@github.com:inspirai-store/skill-market.git", Type: "tools", Product: "independent"},
 		},
@@ -68,6 +111,17 @@ func GetProjectRoot() (string, error) {
 	}
 }
 
+// MirrorDir 返回展开 ~ 之后的缓存目录，未配置缓存时返回空字符串
+func (c *Config) MirrorDir() string {
+	dir := c.CacheDir
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, dir[1:])
+		}
+	}
+	return dir
+}
+
 // GetGitCloneMethod 读取 .bootstrap.conf 获取 git clone 方式
 func GetGitCloneMethod(root string) string {
 	configPath := filepath.Join(root, ".bootstrap.conf")
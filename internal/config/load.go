@@ -0,0 +1,443 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFiles 是 manifest 的候选路径（相对项目根目录），按顺序查找
+var ManifestFiles = []string{
+	"sm.yaml",
+	filepath.Join(".sm", "config.yaml"),
+}
+
+// LocalFile 是开发者个人的覆盖配置，不应提交到仓库
+const LocalFile = "sm.local.yaml"
+
+// EnvVars 定义可以通过环境变量覆盖的配置项
+var EnvVars = map[string]string{
+	"SM_SUBMODULES_DIR": "submodules_dir",
+	"SM_CLONE_METHOD":   "clone_method",
+	"SM_CACHE_DIR":      "cache_dir",
+}
+
+// Source 表示配置值来自哪一层，优先级从低到高排列
+type Source string
+
+const (
+	SourceBuiltin Source = "built-in"
+	SourceProject Source = "project"
+	SourceLocal   Source = "local"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// Origin 记录一个配置值的来源
+type Origin struct {
+	Source Source `json:"source" yaml:"source"`
+	File   string `json:"file,omitempty" yaml:"file,omitempty"` // 相对项目根目录的文件路径，env/flag 时为变量或参数名
+	Line   int    `json:"line,omitempty" yaml:"line,omitempty"`
+	Column int    `json:"column,omitempty" yaml:"column,omitempty"`
+}
+
+func (o Origin) String() string {
+	switch {
+	case o.File == "":
+		return string(o.Source)
+	case o.Line == 0:
+		return fmt.Sprintf("%s (%s)", o.Source, o.File)
+	default:
+		return fmt.Sprintf("%s (%s:%d:%d)", o.Source, o.File, o.Line, o.Column)
+	}
+}
+
+// Options 控制配置的加载过程
+type Options struct {
+	// Overrides 是命令行参数的覆盖值，key 为顶层配置项，如 clone_method
+	Overrides map[string]string
+	// SkipValidation 为 true 时即使配置有错误也返回合并结果
+	SkipValidation bool
+}
+
+// Resolved 是逐层合并后的配置
+type Resolved struct {
+	Config *Config
+	// Node 是合并后的 YAML 文档，节点保留各自来源文件中的行列号
+	Node *yaml.Node
+	// Origins 以配置路径（如 submodules[lingbo-web].repo）为 key 记录每个值的来源
+	Origins map[string]Origin
+
+	// layerProblems 是合并前在单个文件中发现的问题（如重复的名称）
+	layerProblems []Problem
+}
+
+// layer 是参与合并的一层配置
+type layer struct {
+	source Source
+	file   string
+	node   *yaml.Node // mapping 节点
+	// origin 为该层中单个节点生成来源信息
+	origin func(n *yaml.Node, path string) Origin
+}
+
+// FindManifest 返回项目根目录下的 manifest 路径，不存在时返回空字符串
+func FindManifest(root string) string {
+	for _, name := range ManifestFiles {
+		path := filepath.Join(root, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// Load 加载项目配置，等价于不带覆盖参数的 Resolve
+func Load(root string) (*Config, error) {
+	res, err := Resolve(root, Options{})
+	if err != nil {
+		return nil, err
+	}
+	return res.Config, nil
+}
+
+// Resolve 按 built-in < project < local < env < flag 的优先级合并配置
+//
+// 只有在不存在 project manifest 时才会使用内置的 submodule 列表。
+func Resolve(root string, opts Options) (*Resolved, error) {
+	manifest := FindManifest(root)
+
+	layers := []*layer{builtinLayer(root, manifest == "")}
+
+	if manifest != "" {
+		l, err := fileLayer(root, manifest, SourceProject)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, l)
+	}
+
+	localPath := filepath.Join(root, LocalFile)
+	if _, err := os.Stat(localPath); err == nil {
+		l, err := fileLayer(root, localPath, SourceLocal)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, l)
+	}
+
+	env := map[string]string{}
+	envFiles := map[string]string{}
+	for name, key := range EnvVars {
+		if value, ok := os.LookupEnv(name); ok && value != "" {
+			env[key] = value
+			envFiles[key] = name
+		}
+	}
+	if len(env) > 0 {
+		layers = append(layers, scalarLayer(SourceEnv, env, envFiles))
+	}
+
+	if len(opts.Overrides) > 0 {
+		flagFiles := map[string]string{}
+		for key := range opts.Overrides {
+			flagFiles[key] = "--" + flagName(key)
+		}
+		layers = append(layers, scalarLayer(SourceFlag, opts.Overrides, flagFiles))
+	}
+
+	res := &Resolved{Origins: map[string]Origin{}}
+	var merged *yaml.Node
+	for _, l := range layers {
+		res.layerProblems = append(res.layerProblems, checkDuplicateNames(l)...)
+		merged = mergeNode(merged, l.node, "", l, res.Origins)
+	}
+	res.Node = merged
+
+	if !opts.SkipValidation {
+		if problems := res.Validate(); len(problems) > 0 {
+			return nil, &ValidationError{Problems: problems}
+		}
+	}
+
+	var cfg Config
+	if err := merged.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+
+	// 过滤掉被禁用的 submodule
+	enabled := cfg.Submodules[:0]
+	for _, sm := range cfg.Submodules {
+		if sm.Disabled {
+			cfg.Disabled = append(cfg.Disabled, sm.Name)
+		} else {
+			enabled = append(enabled, sm)
+		}
+	}
+	cfg.Submodules = enabled
+	res.Config = &cfg
+
+	return res, nil
+}
+
+// builtinLayer 生成内置默认值；withSubmodules 为 false 时只包含基础设置，
+// 不包含内置的产品和 submodule 列表
+func builtinLayer(root string, withSubmodules bool) *layer {
+	cfg := DefaultConfig()
+	// 兼容旧的 .bootstrap.conf
+	cfg.CloneMethod = GetGitCloneMethod(root)
+	if !withSubmodules {
+		cfg.Products = nil
+		cfg.Submodules = nil
+	}
+
+	var doc yaml.Node
+	// 内置配置一定可以编码，忽略错误
+	_ = doc.Encode(cfg)
+
+	return &layer{
+		source: SourceBuiltin,
+		node:   &doc,
+		origin: func(n *yaml.Node, path string) Origin {
+			return Origin{Source: SourceBuiltin}
+		},
+	}
+}
+
+// fileLayer 读取一个 YAML 配置文件
+func fileLayer(root, path string, source Source) (*layer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		rel = path
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", rel, err)
+	}
+
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if len(doc.Content) > 0 {
+		node = doc.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s:%d:%d: top level must be a mapping", rel, node.Line, node.Column)
+	}
+
+	return &layer{
+		source: source,
+		file:   rel,
+		node:   node,
+		origin: func(n *yaml.Node, path string) Origin {
+			return Origin{Source: source, File: rel, Line: n.Line, Column: n.Column}
+		},
+	}, nil
+}
+
+// scalarLayer 把 key/value 形式的覆盖值（环境变量、命令行参数）转换为一层配置
+func scalarLayer(source Source, values, names map[string]string) *layer {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, key := range keys {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: values[key]},
+		)
+	}
+
+	return &layer{
+		source: source,
+		node:   node,
+		origin: func(n *yaml.Node, path string) Origin {
+			return Origin{Source: source, File: names[path]}
+		},
+	}
+}
+
+// mergeNode 把 src 深度合并到 dst 上并返回结果
+//
+// mapping 按 key 递归合并；元素都带 name 字段的列表按 name 合并，
+// 其余列表和标量整体替换。
+func mergeNode(dst, src *yaml.Node, path string, l *layer, origins map[string]Origin) *yaml.Node {
+	if dst == nil || dst.Kind != src.Kind {
+		clearOrigins(path, origins)
+		record(src, path, l, origins)
+		return src
+	}
+
+	switch {
+	case src.Kind == yaml.DocumentNode:
+		dst.Content[0] = mergeNode(dst.Content[0], src.Content[0], path, l, origins)
+		return dst
+
+	case src.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(src.Content); i += 2 {
+			key, value := src.Content[i], src.Content[i+1]
+			childPath := joinPath(path, key.Value)
+			if j := mappingIndex(dst, key.Value); j >= 0 {
+				dst.Content[j+1] = mergeNode(dst.Content[j+1], value, childPath, l, origins)
+			} else {
+				dst.Content = append(dst.Content, key, value)
+				record(value, childPath, l, origins)
+			}
+		}
+		return dst
+
+	case src.Kind == yaml.SequenceNode && isNamedList(dst) && isNamedList(src):
+		for _, item := range src.Content {
+			name := mappingValue(item, "name")
+			itemPath := fmt.Sprintf("%s[%s]", path, name)
+			if j := namedIndex(dst, name); j >= 0 {
+				dst.Content[j] = mergeNode(dst.Content[j], item, itemPath, l, origins)
+			} else {
+				dst.Content = append(dst.Content, item)
+				record(item, itemPath, l, origins)
+			}
+		}
+		return dst
+	}
+
+	// 被整体替换时清除旧值的来源记录
+	clearOrigins(path, origins)
+	record(src, path, l, origins)
+	return src
+}
+
+// record 递归记录节点及其子节点的来源
+func record(n *yaml.Node, path string, l *layer, origins map[string]Origin) {
+	origins[path] = l.origin(n, path)
+
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			record(c, path, l, origins)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			record(n.Content[i+1], joinPath(path, n.Content[i].Value), l, origins)
+		}
+	case yaml.SequenceNode:
+		for i, item := range n.Content {
+			record(item, itemPath(n, i, path), l, origins)
+		}
+	}
+}
+
+func clearOrigins(path string, origins map[string]Origin) {
+	for key := range origins {
+		if len(key) > len(path) && key[:len(path)] == path && (key[len(path)] == '.' || key[len(path)] == '[') {
+			delete(origins, key)
+		}
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// mappingIndex 返回 mapping 中 key 节点的下标，不存在时返回 -1
+func mappingIndex(n *yaml.Node, key string) int {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// mappingValue 返回 mapping 中 key 对应的标量值
+func mappingValue(n *yaml.Node, key string) string {
+	if n.Kind != yaml.MappingNode {
+		return ""
+	}
+	if i := mappingIndex(n, key); i >= 0 {
+		return n.Content[i+1].Value
+	}
+	return ""
+}
+
+// isNamedList 判断列表的每个元素是否都是带 name 字段的 mapping
+func isNamedList(n *yaml.Node) bool {
+	if n.Kind != yaml.SequenceNode {
+		return false
+	}
+	for _, item := range n.Content {
+		if mappingValue(item, "name") == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func namedIndex(n *yaml.Node, name string) int {
+	for i, item := range n.Content {
+		if mappingValue(item, "name") == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// flagName 把配置项转换为对应的命令行参数名，如 clone_method -> clone-method
+func flagName(key string) string {
+	b := []byte(key)
+	for i := range b {
+		if b[i] == '_' {
+			b[i] = '-'
+		}
+	}
+	return string(b)
+}
+
+// Annotated 返回合并结果的副本，每个标量值的行尾注释标明其来源；
+// flow 风格的列表和映射改为 block 风格，使每个元素都能带上自己的注释
+func (r *Resolved) Annotated() *yaml.Node {
+	out := copyNode(r.Node)
+	annotate(out, "", r.Origins)
+	return out
+}
+
+func annotate(n *yaml.Node, path string, origins map[string]Origin) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if origin, ok := origins[path]; ok {
+			n.LineComment = origin.String()
+		}
+	case yaml.MappingNode:
+		n.Style &^= yaml.FlowStyle
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			n.Content[i].LineComment = ""
+			annotate(n.Content[i+1], joinPath(path, n.Content[i].Value), origins)
+		}
+	case yaml.SequenceNode:
+		n.Style &^= yaml.FlowStyle
+		for i, item := range n.Content {
+			annotate(item, itemPath(n, i, path), origins)
+		}
+	}
+}
+
+func copyNode(n *yaml.Node) *yaml.Node {
+	c := *n
+	c.Content = make([]*yaml.Node, len(n.Content))
+	for i, child := range n.Content {
+		c.Content[i] = copyNode(child)
+	}
+	return &c
+}
@@ -0,0 +1,271 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// writeFiles 在临时目录中写入 files（相对路径 -> 内容），返回该目录
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// clearEnv 清除 SM_* 环境变量，使测试不受运行环境影响
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for name := range EnvVars {
+		t.Setenv(name, "")
+	}
+}
+
+const testManifest = `products: [x, y]
+submodules:
+  - name: foo
+    repo: git@github.com:org/foo.git
+    type: tools
+    product: x
+    tags: [go, cli]
+`
+
+func TestResolveOrigins(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		env       map[string]string
+		overrides map[string]string
+		path      string
+		want      Origin
+	}{
+		{
+			name:  "built-in default",
+			files: map[string]string{"sm.yaml": testManifest},
+			path:  "submodules_dir",
+			want:  Origin{Source: SourceBuiltin},
+		},
+		{
+			name:  "project value",
+			files: map[string]string{"sm.yaml": testManifest},
+			path:  "submodules[foo].repo",
+			want:  Origin{Source: SourceProject, File: "sm.yaml", Line: 4, Column: 11},
+		},
+		{
+			name:  "manifest in .sm",
+			files: map[string]string{".sm/config.yaml": testManifest},
+			path:  "submodules[foo].type",
+			want:  Origin{Source: SourceProject, File: filepath.Join(".sm", "config.yaml"), Line: 5, Column: 11},
+		},
+		{
+			name: "local field of a named item",
+			files: map[string]string{
+				"sm.yaml":       testManifest,
+				"sm.local.yaml": "submodules:\n  - name: foo\n    branch: dev\n",
+			},
+			path: "submodules[foo].branch",
+			want: Origin{Source: SourceLocal, File: "sm.local.yaml", Line: 3, Column: 13},
+		},
+		{
+			name: "untouched field of a merged item",
+			files: map[string]string{
+				"sm.yaml":       testManifest,
+				"sm.local.yaml": "submodules:\n  - name: foo\n    branch: dev\n",
+			},
+			path: "submodules[foo].repo",
+			want: Origin{Source: SourceProject, File: "sm.yaml", Line: 4, Column: 11},
+		},
+		{
+			name: "local item appended",
+			files: map[string]string{
+				"sm.yaml":       testManifest,
+				"sm.local.yaml": "submodules:\n  - name: bar\n    repo: git@github.com:org/bar.git\n    type: service\n    product: y\n",
+			},
+			path: "submodules[bar].type",
+			want: Origin{Source: SourceLocal, File: "sm.local.yaml", Line: 4, Column: 11},
+		},
+		{
+			name: "scalar list replaced",
+			files: map[string]string{
+				"sm.yaml":       testManifest,
+				"sm.local.yaml": "products: [x]\n",
+			},
+			path: "products[0]",
+			want: Origin{Source: SourceLocal, File: "sm.local.yaml", Line: 1, Column: 12},
+		},
+		{
+			name:  "env over project",
+			files: map[string]string{"sm.yaml": testManifest + "clone_method: ssh\n"},
+			env:   map[string]string{"SM_CLONE_METHOD": "https"},
+			path:  "clone_method",
+			want:  Origin{Source: SourceEnv, File: "SM_CLONE_METHOD"},
+		},
+		{
+			name:      "flag over env",
+			files:     map[string]string{"sm.yaml": testManifest},
+			env:       map[string]string{"SM_CLONE_METHOD": "https"},
+			overrides: map[string]string{"clone_method": "ssh"},
+			path:      "clone_method",
+			want:      Origin{Source: SourceFlag, File: "--clone-method"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			root := writeFiles(t, tt.files)
+
+			res, err := Resolve(root, Options{Overrides: tt.overrides})
+			if err != nil {
+				t.Fatalf("Resolve: %v", err)
+			}
+			if got, ok := res.Origins[tt.path]; !ok || got != tt.want {
+				t.Errorf("Origins[%q] = %+v (found %v), want %+v", tt.path, got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveMergesNamedLists(t *testing.T) {
+	clearEnv(t)
+	root := writeFiles(t, map[string]string{
+		"sm.yaml": testManifest,
+		"sm.local.yaml": `products: [x, y, z]
+submodules:
+  - name: bar
+    repo: git@github.com:org/bar.git
+    type: service
+    product: z
+  - name: foo
+    branch: dev
+    tags: [web]
+`,
+	})
+
+	res, err := Resolve(root, Options{})
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+
+	want := []SubmoduleConfig{
+		// 同名条目按字段合并，保持在原来的位置；列表字段整体替换
+		{Name: "foo", Repo: "git@github.com:org/foo.git", Type: "tools", Product: "x", Tags: []string{"web"}, Branch: "dev"},
+		{Name: "bar", Repo: "git@github.com:org/bar.git", Type: "service", Product: "z"},
+	}
+	if !reflect.DeepEqual(res.Config.Submodules, want) {
+		t.Errorf("Submodules = %+v, want %+v", res.Config.Submodules, want)
+	}
+	if want := []string{"x", "y", "z"}; !reflect.DeepEqual(res.Config.Products, want) {
+		t.Errorf("Products = %v, want %v", res.Config.Products, want)
+	}
+
+	// 被替换的列表不应留下旧元素的来源
+	if origin, ok := res.Origins["submodules[foo].tags[1]"]; ok {
+		t.Errorf("stale origin for submodules[foo].tags[1]: %+v", origin)
+	}
+	if got := res.Origins["submodules[foo].tags[0]"].Source; got != SourceLocal {
+		t.Errorf("submodules[foo].tags[0] source = %s, want %s", got, SourceLocal)
+	}
+}
+
+func TestResolveBuiltinOnlyWithoutManifest(t *testing.T) {
+	clearEnv(t)
+
+	cfg, err := Load(t.TempDir())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	defaults := DefaultConfig()
+	if !reflect.DeepEqual(cfg.Products, defaults.Products) || len(cfg.Submodules) != len(defaults.Submodules) {
+		t.Errorf("without a manifest got %d products and %d submodules, want the built-in lists", len(cfg.Products), len(cfg.Submodules))
+	}
+
+	// manifest 没有 products 时不使用内置的产品列表
+	root := writeFiles(t, map[string]string{"sm.yaml": `submodules:
+  - name: foo
+    repo: git@github.com:org/foo.git
+    type: tools
+    product: p1
+`})
+	cfg, err = Load(root)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(cfg.Products) != 0 || len(cfg.Submodules) != 1 {
+		t.Errorf("Products = %v, Submodules = %+v, want only the manifest", cfg.Products, cfg.Submodules)
+	}
+}
+
+func TestResolveDisabled(t *testing.T) {
+	clearEnv(t)
+	root := writeFiles(t, map[string]string{
+		"sm.yaml":       testManifest,
+		"sm.local.yaml": "submodules:\n  - name: foo\n    disabled: true\n",
+	})
+
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(cfg.Submodules) != 0 {
+		t.Errorf("Submodules = %+v, want none", cfg.Submodules)
+	}
+	if want := []string{"foo"}; !reflect.DeepEqual(cfg.Disabled, want) {
+		t.Errorf("Disabled = %v, want %v", cfg.Disabled, want)
+	}
+}
+
+func TestResolvedAnnotated(t *testing.T) {
+	clearEnv(t)
+	root := writeFiles(t, map[string]string{"sm.yaml": testManifest})
+
+	res, err := Resolve(root, Options{})
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	data, err := yaml.Marshal(res.Annotated())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+
+	// flow 风格的列表改为 block 风格，每个元素带自己的来源
+	for _, want := range []string{"- x # project (sm.yaml:1:12)", "- go # project (sm.yaml:7:12)", "- cli # project (sm.yaml:7:16)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("annotated output does not contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "[") {
+		t.Errorf("annotated output still has a flow sequence:\n%s", out)
+	}
+	var parsed Config
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("annotated output is not valid YAML: %v\n%s", err, out)
+	}
+	if !reflect.DeepEqual(parsed.Submodules[0].Tags, []string{"go", "cli"}) {
+		t.Errorf("tags = %v after a round trip", parsed.Submodules[0].Tags)
+	}
+
+	// 合并结果本身不受影响
+	for i := 0; i+1 < len(res.Node.Content); i += 2 {
+		if res.Node.Content[i].Value == "products" && res.Node.Content[i+1].Style&yaml.FlowStyle == 0 {
+			t.Error("Annotated changed the style of the resolved node")
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LockFile 是锁文件名（相对项目根目录），记录一组可以一起工作的提交
+const LockFile = "sm.lock"
+
+// Lock 是 sm.lock 的内容
+type Lock struct {
+	Submodules []LockedRepo `json:"submodules" yaml:"submodules"`
+}
+
+// LockedRepo 记录单个 submodule 被锁定的提交
+type LockedRepo struct {
+	Name   string `json:"name" yaml:"name"`
+	Commit string `json:"commit" yaml:"commit"`
+	Branch string `json:"branch,omitempty" yaml:"branch,omitempty"` // 锁定时所在的分支，detached 时为空
+	Remote string `json:"remote,omitempty" yaml:"remote,omitempty"`
+}
+
+// Find 按名称查找锁定记录
+func (l *Lock) Find(name string) (LockedRepo, bool) {
+	for _, repo := range l.Submodules {
+		if repo.Name == name {
+			return repo, true
+		}
+	}
+	return LockedRepo{}, false
+}
+
+// LoadLock 读取项目根目录下的 sm.lock
+func LoadLock(root string) (*Lock, error) {
+	data, err := os.ReadFile(filepath.Join(root, LockFile))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s not found, run sm lock first: %w", LockFile, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", LockFile, err)
+	}
+
+	var lock Lock
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", LockFile, err)
+	}
+	return &lock, nil
+}
+
+// SaveLock 写入 sm.lock
+func SaveLock(root string, lock *Lock) error {
+	var buf bytes.Buffer
+	buf.WriteString("# Code generated by sm lock. DO NOT EDIT.\n")
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(lock); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(root, LockFile), buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", LockFile, err)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AddSubmodule 把 submodule 追加到项目 manifest 的 submodules 列表末尾，返回 manifest 的路径
+//
+// 文件按 YAML 节点修改，保留原有的注释和顺序。没有 manifest 时先用内置的
+// submodule 列表创建 sm.yaml。写入后重新校验配置，有错误时恢复原文件。
+func AddSubmodule(root string, sm SubmoduleConfig) (string, error) {
+	path := FindManifest(root)
+	var original []byte
+	var doc *yaml.Node
+
+	if path == "" {
+		path = filepath.Join(root, ManifestFiles[0])
+		defaults := DefaultConfig()
+		doc = &yaml.Node{Kind: yaml.DocumentNode}
+		var builtin yaml.Node
+		if err := builtin.Encode(map[string]any{"products": defaults.Products, "submodules": defaults.Submodules}); err != nil {
+			return "", err
+		}
+		child(&builtin, "products").Style = yaml.FlowStyle
+		doc.Content = []*yaml.Node{&builtin}
+	} else {
+		var err error
+		if original, doc, err = readManifest(path); err != nil {
+			return "", err
+		}
+	}
+
+	submodules, err := sequenceOf(doc.Content[0], "submodules")
+	if err != nil {
+		return "", err
+	}
+	if namedIndex(submodules, sm.Name) >= 0 {
+		return "", fmt.Errorf("submodule %q already exists in %s", sm.Name, filepath.Base(path))
+	}
+
+	var item yaml.Node
+	if err := item.Encode(sm); err != nil {
+		return "", err
+	}
+	// 与手写的 manifest 一致，标量列表写成 [a, b]
+	for _, value := range item.Content {
+		if value.Kind == yaml.SequenceNode {
+			value.Style = yaml.FlowStyle
+		}
+	}
+	// submodules: [] 是 flow 风格，追加的条目仍然写成多行
+	submodules.Style &^= yaml.FlowStyle
+	submodules.Content = append(submodules.Content, &item)
+
+	return path, writeManifest(root, path, doc, original)
+}
+
+// RepoName 从仓库地址推断 submodule 名称，如 git@github.com:org/lingbo-web.git -> lingbo-web
+func RepoName(repo string) string {
+	name := strings.TrimRight(repo, "/")
+	name = name[strings.LastIndexAny(name, "/:")+1:]
+	return strings.TrimSuffix(name, ".git")
+}
+
+// RemoveSubmodule 从项目 manifest 中删除 submodule 及 profile 中对它的引用，返回 manifest 的路径
+//
+// 如果某个 profile 只列出了这个 submodule，删除后它会变成"全部 submodule"，
+// 此时拒绝修改，需要先编辑或删除该 profile。
+func RemoveSubmodule(root, name string) (string, error) {
+	path := FindManifest(root)
+	if path == "" {
+		return "", fmt.Errorf("no manifest found (%s); built-in submodules cannot be removed", ManifestFiles[0])
+	}
+	original, doc, err := readManifest(path)
+	if err != nil {
+		return "", err
+	}
+
+	top := doc.Content[0]
+	submodules := child(top, "submodules")
+	i := -1
+	if submodules != nil && submodules.Kind == yaml.SequenceNode {
+		i = namedIndex(submodules, name)
+	}
+	if i < 0 {
+		return "", fmt.Errorf("submodule %q is not defined in %s", name, filepath.Base(path))
+	}
+	submodules.Content = slices.Delete(submodules.Content, i, i+1)
+
+	if profiles := child(top, "profiles"); profiles != nil && profiles.Kind == yaml.SequenceNode {
+		for _, profile := range profiles.Content {
+			emptied := false
+			for _, key := range []string{"repos", "exclude"} {
+				if list := child(profile, key); list != nil && list.Kind == yaml.SequenceNode {
+					n := len(list.Content)
+					list.Content = slices.DeleteFunc(list.Content, func(n *yaml.Node) bool { return n.Value == name })
+					emptied = emptied || key == "repos" && n > 0 && len(list.Content) == 0
+				}
+			}
+			if emptied && !hasFilter(profile) {
+				return "", fmt.Errorf("profile %q only contains %q and would select every submodule without it; edit or delete the profile first",
+					mappingValue(profile, "name"), name)
+			}
+		}
+	}
+
+	return path, writeManifest(root, path, doc, original)
+}
+
+// hasFilter 判断 profile 是否设置了 products、types、tags 或 owners
+func hasFilter(profile *yaml.Node) bool {
+	for _, key := range []string{"products", "types", "tags", "owners"} {
+		if list := child(profile, key); list != nil && len(list.Content) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// readManifest 读取 manifest，返回原始内容和顶层为 mapping 的文档节点
+func readManifest(path string) ([]byte, *yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("%s: top level must be a mapping", path)
+	}
+	return data, &doc, nil
+}
+
+// sequenceOf 返回 mapping 中 key 对应的列表，不存在时创建一个空列表
+func sequenceOf(n *yaml.Node, key string) (*yaml.Node, error) {
+	if seq := child(n, key); seq != nil {
+		if seq.Kind != yaml.SequenceNode {
+			return nil, fmt.Errorf("%s must be a list", key)
+		}
+		return seq, nil
+	}
+	seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, seq)
+	return seq, nil
+}
+
+// writeManifest 写入修改后的 manifest 并重新校验配置；校验失败时恢复为 original
+// （original 为空表示文件原本不存在）
+func writeManifest(root, path string, doc *yaml.Node, original []byte) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	if _, err := Resolve(root, Options{}); err != nil {
+		if original == nil {
+			os.Remove(path)
+		} else {
+			os.WriteFile(path, original, 0644)
+		}
+		return err
+	}
+	return nil
+}
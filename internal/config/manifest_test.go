@@ -0,0 +1,287 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const commentedManifest = `# 项目 manifest
+products: [x, y]
+
+submodules:
+  # 工具
+  - name: foo
+    repo: git@github.com:org/foo.git
+    type: tools
+    product: x # 主产品
+  - name: bar
+    repo: git@github.com:org/bar.git
+    type: service
+    product: y
+
+profiles:
+  - name: tools
+    types: [tools]
+    repos: [bar]
+  - name: all-but-bar
+    exclude: [bar]
+`
+
+func TestAddSubmodule(t *testing.T) {
+	baz := SubmoduleConfig{Name: "baz", Repo: "git@github.com:org/baz.git", Type: "client", Product: "x", Tags: []string{"web", "ts"}}
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		sm      SubmoduleConfig
+		want    string // 写入后的 manifest，为空时不比较
+		wantErr string // 非空时期望的错误，manifest 保持不变
+	}{
+		{
+			name:  "append keeps comments",
+			files: map[string]string{"sm.yaml": commentedManifest},
+			sm:    baz,
+			want: `# 项目 manifest
+products: [x, y]
+submodules:
+  # 工具
+  - name: foo
+    repo: git@github.com:org/foo.git
+    type: tools
+    product: x # 主产品
+  - name: bar
+    repo: git@github.com:org/bar.git
+    type: service
+    product: y
+  - name: baz
+    repo: git@github.com:org/baz.git
+    type: client
+    product: x
+    tags: [web, ts]
+profiles:
+  - name: tools
+    types: [tools]
+    repos: [bar]
+  - name: all-but-bar
+    exclude: [bar]
+`,
+		},
+		{
+			name:    "duplicate name",
+			files:   map[string]string{"sm.yaml": commentedManifest},
+			sm:      SubmoduleConfig{Name: "foo", Repo: "git@github.com:org/foo.git", Type: "tools", Product: "x"},
+			wantErr: `submodule "foo" already exists in sm.yaml`,
+		},
+		{
+			name:    "invalid entry is rolled back",
+			files:   map[string]string{"sm.yaml": commentedManifest},
+			sm:      SubmoduleConfig{Name: "baz", Repo: "git@github.com:org/baz.git", Type: "client", Product: "z"},
+			wantErr: `unknown product "z"`,
+		},
+		{
+			name:  "manifest in .sm",
+			files: map[string]string{".sm/config.yaml": "products: [x]\nsubmodules: []\n"},
+			sm:    SubmoduleConfig{Name: "baz", Repo: "git@github.com:org/baz.git", Type: "tools", Product: "x"},
+			want: `products: [x]
+submodules:
+  - name: baz
+    repo: git@github.com:org/baz.git
+    type: tools
+    product: x
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			root := writeFiles(t, tt.files)
+			manifest := FindManifest(root)
+			before := readFile(t, manifest)
+
+			path, err := AddSubmodule(root, tt.sm)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("AddSubmodule error = %v, want %q", err, tt.wantErr)
+				}
+				if got := readFile(t, manifest); got != before {
+					t.Errorf("manifest changed after error:\n%s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AddSubmodule: %v", err)
+			}
+			if path != manifest {
+				t.Errorf("path = %s, want %s", path, manifest)
+			}
+			if got := readFile(t, path); got != tt.want {
+				t.Errorf("manifest:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddSubmoduleWithoutManifest(t *testing.T) {
+	clearEnv(t)
+	root := t.TempDir()
+
+	// 校验失败时不留下新建的 sm.yaml
+	if _, err := AddSubmodule(root, SubmoduleConfig{Name: "baz", Repo: "git@github.com:org/baz.git", Type: "daemon", Product: "zenix"}); err == nil {
+		t.Fatal("AddSubmodule with an unknown type succeeded")
+	}
+	if path := FindManifest(root); path != "" {
+		t.Fatalf("%s was left behind", path)
+	}
+
+	path, err := AddSubmodule(root, SubmoduleConfig{Name: "baz", Repo: "git@github.com:org/baz.git", Type: "tools", Product: "zenix"})
+	if err != nil {
+		t.Fatalf("AddSubmodule: %v", err)
+	}
+	if path != filepath.Join(root, "sm.yaml") {
+		t.Errorf("path = %s, want sm.yaml", path)
+	}
+
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	builtin := DefaultConfig().Submodules
+	if got, want := len(cfg.Submodules), len(builtin)+1; got != want {
+		t.Fatalf("%d submodules, want %d", got, want)
+	}
+	if last := cfg.Submodules[len(cfg.Submodules)-1]; last.Name != "baz" {
+		t.Errorf("last submodule = %s, want baz", last.Name)
+	}
+}
+
+func TestRemoveSubmodule(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		remove  string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "remove entry and profile references",
+			files:  map[string]string{"sm.yaml": commentedManifest},
+			remove: "bar",
+			want: `# 项目 manifest
+products: [x, y]
+submodules:
+  # 工具
+  - name: foo
+    repo: git@github.com:org/foo.git
+    type: tools
+    product: x # 主产品
+profiles:
+  - name: tools
+    types: [tools]
+    repos: []
+  - name: all-but-bar
+    exclude: []
+`,
+		},
+		{
+			name:   "comment of the removed entry goes with it",
+			files:  map[string]string{"sm.yaml": commentedManifest},
+			remove: "foo",
+			want: `# 项目 manifest
+products: [x, y]
+submodules:
+  - name: bar
+    repo: git@github.com:org/bar.git
+    type: service
+    product: y
+profiles:
+  - name: tools
+    types: [tools]
+    repos: [bar]
+  - name: all-but-bar
+    exclude: [bar]
+`,
+		},
+		{
+			name: "profile would select everything",
+			files: map[string]string{"sm.yaml": commentedManifest + `  - name: only-bar
+    repos: [bar]
+`},
+			remove:  "bar",
+			wantErr: `profile "only-bar" only contains "bar"`,
+		},
+		{
+			name:    "unknown submodule",
+			files:   map[string]string{"sm.yaml": commentedManifest},
+			remove:  "baz",
+			wantErr: `submodule "baz" is not defined in sm.yaml`,
+		},
+		{
+			name: "local override is rolled back",
+			files: map[string]string{
+				"sm.yaml":       commentedManifest,
+				"sm.local.yaml": "submodules:\n  - name: bar\n    branch: dev\n",
+			},
+			remove:  "bar",
+			wantErr: `submodule "bar" is missing a repo`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			root := writeFiles(t, tt.files)
+			manifest := FindManifest(root)
+			before := readFile(t, manifest)
+
+			path, err := RemoveSubmodule(root, tt.remove)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("RemoveSubmodule error = %v, want %q", err, tt.wantErr)
+				}
+				if got := readFile(t, manifest); got != before {
+					t.Errorf("manifest changed after error:\n%s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RemoveSubmodule: %v", err)
+			}
+			if got := readFile(t, path); got != tt.want {
+				t.Errorf("manifest:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveSubmoduleWithoutManifest(t *testing.T) {
+	clearEnv(t)
+	if _, err := RemoveSubmodule(t.TempDir(), "lingbo-web"); err == nil || !strings.Contains(err.Error(), "no manifest found") {
+		t.Errorf("RemoveSubmodule error = %v, want no manifest found", err)
+	}
+}
+
+func TestRepoName(t *testing.T) {
+	tests := map[string]string{
+		"git@github.com:org/lingbo-web.git":     "lingbo-web",
+		"https://github.com/org/lingbo-web.git": "lingbo-web",
+		"https://github.com/org/lingbo-web/":    "lingbo-web",
+		"/srv/git/tools":                        "tools",
+	}
+	for repo, want := range tests {
+		if got := RepoName(repo); got != want {
+			t.Errorf("RepoName(%q) = %q, want %q", repo, got, want)
+		}
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Profile 是 manifest 中预定义的一组 submodule，如 frontend、platform-backend
+//
+// Products、Types、Tags 和 Owners 同时出现时取交集（Tags 和 Owners 内部只需匹配一个），
+// Repos 中列出的 submodule 总是包含在内；全部为空时表示全部 submodule。
+type Profile struct {
+	Name        string   `json:"name" yaml:"name"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Products    []string `json:"products,omitempty" yaml:"products,omitempty"`
+	Types       []string `json:"types,omitempty" yaml:"types,omitempty"`
+	Tags        []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Owners      []string `json:"owners,omitempty" yaml:"owners,omitempty"`
+	Repos       []string `json:"repos,omitempty" yaml:"repos,omitempty"`
+	Exclude     []string `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+}
+
+// Match 判断 submodule 是否属于该 profile
+func (p Profile) Match(sm SubmoduleConfig) bool {
+	if slices.Contains(p.Exclude, sm.Name) {
+		return false
+	}
+	if slices.Contains(p.Repos, sm.Name) {
+		return true
+	}
+
+	filter := Selection{Products: p.Products, Types: p.Types, Tags: p.Tags, Owners: p.Owners}
+	if filter.IsEmpty() {
+		// 只列出了 repos 时不包含其他 submodule
+		return len(p.Repos) == 0
+	}
+	return filter.Match(sm)
+}
+
+// Profile 按名称查找 profile
+func (c *Config) Profile(name string) (*Profile, error) {
+	for i := range c.Profiles {
+		if c.Profiles[i].Name == name {
+			return &c.Profiles[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown profile %q", name)
+}
+
+// SelectProfile 返回只包含 profile 中 submodule 的配置副本
+func (c *Config) SelectProfile(p *Profile) *Config {
+	out := *c
+	out.Submodules = nil
+	for _, sm := range c.Submodules {
+		if p.Match(sm) {
+			out.Submodules = append(out.Submodules, sm)
+		}
+	}
+	return &out
+}
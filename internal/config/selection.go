@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Selection 描述要操作的 submodule 子集，为空时表示全部
+//
+// 非空的条件之间是"与"的关系，Exclude 最后生效。
+type Selection struct {
+	Products []string `yaml:"products,omitempty"`
+	Types    []string `yaml:"types,omitempty"`
+	Tags     []string `yaml:"tags,omitempty"`   // 带有其中任意一个标签
+	Owners   []string `yaml:"owners,omitempty"` // 属于其中任意一个负责人
+	Only     []string `yaml:"only,omitempty"`
+	Exclude  []string `yaml:"exclude,omitempty"`
+}
+
+// IsEmpty 判断是否没有任何过滤条件
+func (s Selection) IsEmpty() bool {
+	return len(s.Products) == 0 && len(s.Types) == 0 && len(s.Tags) == 0 && len(s.Owners) == 0 &&
+		len(s.Only) == 0 && len(s.Exclude) == 0
+}
+
+// Match 判断 submodule 是否被选中
+func (s Selection) Match(sm SubmoduleConfig) bool {
+	if len(s.Products) > 0 && !slices.Contains(s.Products, sm.Product) {
+		return false
+	}
+	if len(s.Types) > 0 && !slices.Contains(s.Types, sm.Type) {
+		return false
+	}
+	if len(s.Tags) > 0 && !containsAny(sm.Tags, s.Tags) {
+		return false
+	}
+	if len(s.Owners) > 0 && !containsAny(sm.Owners, s.Owners) {
+		return false
+	}
+	if len(s.Only) > 0 && !slices.Contains(s.Only, sm.Name) {
+		return false
+	}
+	return !slices.Contains(s.Exclude, sm.Name)
+}
+
+// Check 检查过滤条件中引用的产品、类型、标签、负责人和名称是否存在
+func (s Selection) Check(cfg *Config) error {
+	names := make([]string, len(cfg.Submodules))
+	var tags, owners []string
+	for i, sm := range cfg.Submodules {
+		names[i] = sm.Name
+		tags = append(tags, sm.Tags...)
+		owners = append(owners, sm.Owners...)
+	}
+
+	var problems []string
+	unknown := func(kind string, values, known []string) {
+		for _, v := range values {
+			if !slices.Contains(known, v) {
+				problems = append(problems, fmt.Sprintf("unknown %s %q", kind, v))
+			}
+		}
+	}
+	if len(cfg.Products) > 0 {
+		unknown("product", s.Products, cfg.Products)
+	}
+	unknown("type", s.Types, KnownTypes)
+	unknown("tag", s.Tags, tags)
+	unknown("owner", s.Owners, owners)
+	unknown("submodule", s.Only, names)
+	unknown("submodule", s.Exclude, names)
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid selection: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+func (s Selection) String() string {
+	if s.IsEmpty() {
+		return "all"
+	}
+
+	var parts []string
+	add := func(key string, values []string) {
+		if len(values) > 0 {
+			parts = append(parts, key+"="+strings.Join(values, ","))
+		}
+	}
+	add("product", s.Products)
+	add("type", s.Types)
+	add("tag", s.Tags)
+	add("owner", s.Owners)
+	add("only", s.Only)
+	add("exclude", s.Exclude)
+	return strings.Join(parts, " ")
+}
+
+// Select 返回只包含选中 submodule 的配置副本
+func (c *Config) Select(s Selection) *Config {
+	out := *c
+	out.Submodules = nil
+	for _, sm := range c.Submodules {
+		if s.Match(sm) {
+			out.Submodules = append(out.Submodules, sm)
+		}
+	}
+	return &out
+}
+
+// containsAny 判断 values 中是否包含 wanted 的任意一个
+func containsAny(values, wanted []string) bool {
+	return slices.ContainsFunc(wanted, func(w string) bool { return slices.Contains(values, w) })
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StateFile 保存本地工作区状态（相对项目根目录），不应提交到仓库
+var StateFile = filepath.Join(".sm", "state.yaml")
+
+// State 记录开发者在本地工作区中做出的选择
+type State struct {
+	// Profile 是当前启用的 manifest profile，与 Selection 互斥
+	Profile string `yaml:"profile,omitempty"`
+	// Selection 是 sm init 时选择 clone 的 submodule 子集
+	Selection Selection `yaml:"selection,omitempty"`
+	// Links 记录 sm links 创建的软链（相对项目根目录的路径）及其指向的 submodule，
+	// 只有这些软链会在不再需要时被清理
+	Links map[string]string `yaml:"links,omitempty"`
+}
+
+// LoadState 读取本地状态，文件不存在时返回空状态
+func LoadState(root string) (*State, error) {
+	path := filepath.Join(root, StateFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &State{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+
+	var state State
+	if err := yaml.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", StateFile, err)
+	}
+	return &state, nil
+}
+
+// SaveState 写入本地状态
+func SaveState(root string, state *State) error {
+	path := filepath.Join(root, StateFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state dir: %w", err)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(state); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	return nil
+}
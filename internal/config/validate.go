@@ -0,0 +1,480 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KnownTypes 是 submodule 支持的类型
+var KnownTypes = []string{"service", "client", "specs", "tools"}
+
+// KnownStrategies 是 sm sync 支持的同步方式
+var KnownStrategies = []string{"rebase", "merge", "ff-only", "fetch"}
+
+// KnownCloneMethods 是支持的 git clone 方式
+var KnownCloneMethods = []string{"ssh", "https"}
+
+var (
+	// 名称会被用作目录名和软链名
+	namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+	// git@github.com:org/repo.git 形式的 scp 风格地址
+	scpPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:[A-Za-z0-9._~/-]+$`)
+	// blob:limit=1m 形式的部分克隆 filter
+	blobLimitPattern = regexp.MustCompile(`^blob:limit=[0-9]+[kmg]?$`)
+	// 缩写或完整的提交 SHA
+	revisionPattern = regexp.MustCompile(`^[0-9a-fA-F]{4,64}$`)
+)
+
+// Problem 描述配置中的一个错误
+type Problem struct {
+	Origin  Origin `json:"origin" yaml:"origin"`
+	Message string `json:"message" yaml:"message"`
+}
+
+func (p Problem) String() string {
+	if p.Origin.Line == 0 {
+		return fmt.Sprintf("%s: %s", p.Origin, p.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", p.Origin.File, p.Origin.Line, p.Origin.Column, p.Message)
+}
+
+// ValidationError 汇总配置中的所有错误
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid configuration (%d problems):", len(e.Problems))
+	for _, p := range e.Problems {
+		fmt.Fprintf(&b, "\n  %s", p)
+	}
+	return b.String()
+}
+
+// Validate 校验合并后的配置，返回所有问题及其在配置文件中的位置
+func (r *Resolved) Validate() []Problem {
+	v := &validator{origins: r.Origins}
+	v.problems = append(v.problems, r.layerProblems...)
+
+	root := r.Node
+	v.checkSubmodulesDir(root)
+	v.checkOneOf(root, "", "clone_method", KnownCloneMethods)
+
+	products := sequenceValues(child(root, "products"))
+
+	submodules := child(root, "submodules")
+	if submodules != nil && submodules.Kind != yaml.SequenceNode {
+		v.add("submodules", submodules, "submodules must be a list")
+		return v.problems
+	}
+	var names, tags, owners []string
+	if submodules != nil {
+		for i, item := range submodules.Content {
+			v.checkSubmodule(itemPath(submodules, i, "submodules"), item, products)
+			names = append(names, mappingValue(item, "name"))
+			tags = append(tags, sequenceValues(child(item, "tags"))...)
+			owners = append(owners, sequenceValues(child(item, "owners"))...)
+		}
+	}
+
+	if profiles := child(root, "profiles"); profiles != nil {
+		if profiles.Kind != yaml.SequenceNode {
+			v.add("profiles", profiles, "profiles must be a list")
+		} else {
+			for i, item := range profiles.Content {
+				v.checkProfile(itemPath(profiles, i, "profiles"), item, products, names, tags, owners)
+			}
+		}
+	}
+
+	if views := child(root, "views"); views != nil {
+		if views.Kind != yaml.SequenceNode {
+			v.add("views", views, "views must be a list")
+		} else {
+			for i, item := range views.Content {
+				v.checkView(itemPath(views, i, "views"), item)
+			}
+		}
+	}
+
+	// 按文件位置排序，便于对照修改
+	slices.SortStableFunc(v.problems, func(a, b Problem) int {
+		if c := strings.Compare(a.Origin.File, b.Origin.File); c != 0 {
+			return c
+		}
+		if a.Origin.Line != b.Origin.Line {
+			return a.Origin.Line - b.Origin.Line
+		}
+		return a.Origin.Column - b.Origin.Column
+	})
+
+	return v.problems
+}
+
+type validator struct {
+	origins  map[string]Origin
+	problems []Problem
+}
+
+// add 记录一个问题；n 不为空时使用节点自身的行列号，
+// 以便同名条目（来源记录会互相覆盖）也能定位准确
+func (v *validator) add(path string, n *yaml.Node, format string, args ...any) {
+	origin := v.origins[path]
+	if n != nil && n.Line > 0 && origin.Line > 0 {
+		origin.Line, origin.Column = n.Line, n.Column
+	}
+	v.problems = append(v.problems, Problem{
+		Origin:  origin,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) checkSubmodulesDir(root *yaml.Node) {
+	dir := child(root, "submodules_dir")
+	if dir == nil || dir.Value == "" {
+		v.add("", nil, "submodules_dir is required")
+		return
+	}
+	if filepath.IsAbs(dir.Value) || !filepath.IsLocal(dir.Value) {
+		v.add("submodules_dir", dir, "submodules_dir %q must be a relative path inside the project", dir.Value)
+	}
+}
+
+// checkOneOf 检查 mapping 中 key 对应的值是否属于 allowed
+func (v *validator) checkOneOf(item *yaml.Node, path, key string, allowed []string) {
+	n := child(item, key)
+	if n == nil {
+		return
+	}
+	if !slices.Contains(allowed, n.Value) {
+		v.add(joinPath(path, key), n, "unknown %s %q (expected one of: %s)", key, n.Value, strings.Join(allowed, ", "))
+	}
+}
+
+func (v *validator) checkSubmodule(path string, item *yaml.Node, products []string) {
+	if item.Kind != yaml.MappingNode {
+		v.add(path, item, "submodule entry must be a mapping")
+		return
+	}
+
+	name := child(item, "name")
+	switch {
+	case name == nil || name.Value == "":
+		v.add(path, item, "submodule is missing a name")
+	case !validName(name.Value):
+		v.add(path+".name", name, "name %q is not path-safe (letters, digits, '.', '_' and '-' only)", name.Value)
+	}
+	if alias := child(item, "alias"); alias != nil && !validName(alias.Value) {
+		v.add(path+".alias", alias, "alias %q is not path-safe (letters, digits, '.', '_' and '-' only)", alias.Value)
+	}
+
+	// 被禁用的条目通常只在 sm.local.yaml 中写了 name
+	if disabled := child(item, "disabled"); disabled != nil && disabled.Value == "true" {
+		return
+	}
+
+	repo := child(item, "repo")
+	switch {
+	case repo == nil || repo.Value == "":
+		v.add(path, item, "submodule %q is missing a repo", mappingValue(item, "name"))
+	case !validRepoURL(repo.Value):
+		v.add(path+".repo", repo, "malformed repo URL %q", repo.Value)
+	}
+
+	typ := child(item, "type")
+	switch {
+	case typ == nil || typ.Value == "":
+		v.add(path, item, "submodule %q is missing a type", mappingValue(item, "name"))
+	case !slices.Contains(KnownTypes, typ.Value):
+		v.add(path+".type", typ, "unknown type %q (expected one of: %s)", typ.Value, strings.Join(KnownTypes, ", "))
+	}
+
+	product := child(item, "product")
+	switch {
+	case product == nil || product.Value == "":
+		v.add(path, item, "submodule %q is missing a product", mappingValue(item, "name"))
+	case len(products) > 0 && !slices.Contains(products, product.Value):
+		v.add(path+".product", product, "unknown product %q (expected one of: %s)", product.Value, strings.Join(products, ", "))
+	}
+
+	v.checkLabels(path, item, "tags")
+	v.checkLabels(path, item, "owners")
+	v.checkCloneOptions(path, item)
+	v.checkRefs(path, item)
+	v.checkOneOf(item, path, "strategy", KnownStrategies)
+}
+
+// checkLabels 检查 tags、owners 是字符串列表；值会被软链视图用作目录名，不能包含路径分隔符
+func (v *validator) checkLabels(path string, item *yaml.Node, key string) {
+	list := child(item, key)
+	if list == nil {
+		return
+	}
+	if list.Kind != yaml.SequenceNode {
+		v.add(path+"."+key, list, "%s must be a list", key)
+		return
+	}
+	for i, value := range list.Content {
+		if value.Kind != yaml.ScalarNode || value.Value == "" || value.Value == "." || value.Value == ".." || strings.ContainsAny(value.Value, `/\`) {
+			v.add(fmt.Sprintf("%s.%s[%d]", path, key, i), value, "invalid %s %q (must be non-empty and contain no path separators)", strings.TrimSuffix(key, "s"), value.Value)
+		}
+	}
+}
+
+func (v *validator) checkRefs(path string, item *yaml.Node) {
+	for _, key := range []string{"branch", "tag"} {
+		if ref := child(item, key); ref != nil && !validRefName(ref.Value) {
+			v.add(path+"."+key, ref, "invalid %s name %q", key, ref.Value)
+		}
+	}
+
+	tag, revision := child(item, "tag"), child(item, "revision")
+	shallow := positiveInt(child(item, "depth")) || mappingValue(item, "single_branch") == "true"
+	switch {
+	case revision == nil:
+	case !revisionPattern.MatchString(revision.Value):
+		v.add(path+".revision", revision, "revision %q must be a commit SHA (4-64 hex characters)", revision.Value)
+	case shallow && len(revision.Value) < 40:
+		// 浅克隆和单分支 clone 需要按 SHA 获取提交，git fetch 不接受缩写
+		v.add(path+".revision", revision, "revision %q must be a full 40-character SHA when depth or single_branch is set", revision.Value)
+	}
+	if tag != nil && revision != nil {
+		v.add(path+".revision", revision, "tag and revision cannot both be set")
+	}
+}
+
+func (v *validator) checkCloneOptions(path string, item *yaml.Node) {
+	if depth := child(item, "depth"); depth != nil {
+		if n, err := strconv.Atoi(depth.Value); err != nil || n < 0 {
+			v.add(path+".depth", depth, "depth must be a non-negative integer, got %q", depth.Value)
+		}
+	}
+
+	if filter := child(item, "filter"); filter != nil && !validFilter(filter.Value) {
+		v.add(path+".filter", filter, "unsupported filter %q (expected blob:none, tree:0 or blob:limit=<size>)", filter.Value)
+	}
+
+	if sparse := child(item, "sparse"); sparse != nil {
+		if sparse.Kind != yaml.SequenceNode {
+			v.add(path+".sparse", sparse, "sparse must be a list of directories")
+			return
+		}
+		for i, dir := range sparse.Content {
+			if !filepath.IsLocal(dir.Value) {
+				v.add(fmt.Sprintf("%s.sparse[%d]", path, i), dir, "sparse path %q must be relative to the repository", dir.Value)
+			}
+		}
+	}
+}
+
+func (v *validator) checkProfile(path string, item *yaml.Node, products, names, tags, owners []string) {
+	if item.Kind != yaml.MappingNode {
+		v.add(path, item, "profile entry must be a mapping")
+		return
+	}
+
+	name := child(item, "name")
+	if name == nil || name.Value == "" {
+		v.add(path, item, "profile is missing a name")
+	}
+
+	v.checkList(path, item, "products", "product", products)
+	v.checkList(path, item, "types", "type", KnownTypes)
+	v.checkList(path, item, "tags", "tag", tags)
+	v.checkList(path, item, "owners", "owner", owners)
+	v.checkList(path, item, "repos", "submodule", names)
+	v.checkList(path, item, "exclude", "submodule", names)
+}
+
+func (v *validator) checkView(path string, item *yaml.Node) {
+	if item.Kind != yaml.MappingNode {
+		v.add(path, item, "view entry must be a mapping")
+		return
+	}
+
+	name := child(item, "name")
+	switch {
+	case name == nil || name.Value == "":
+		v.add(path, item, "view is missing a name")
+	case !validName(name.Value):
+		v.add(path+".name", name, "view name %q is not path-safe (letters, digits, '.', '_' and '-' only)", name.Value)
+	}
+
+	groupBy := child(item, "group_by")
+	switch {
+	case groupBy == nil || groupBy.Value == "":
+		v.add(path, item, "view %q is missing group_by", mappingValue(item, "name"))
+	case !slices.Contains(KnownGroupKeys, groupBy.Value):
+		v.add(path+".group_by", groupBy, "unknown group_by %q (expected one of: %s)", groupBy.Value, strings.Join(KnownGroupKeys, ", "))
+	}
+
+	if groups := child(item, "groups"); groups != nil {
+		if groups.Kind != yaml.MappingNode {
+			v.add(path+".groups", groups, "groups must be a mapping from group to directory name")
+		} else {
+			for i := 0; i+1 < len(groups.Content); i += 2 {
+				if dir := groups.Content[i+1]; !validName(dir.Value) {
+					v.add(joinPath(path+".groups", groups.Content[i].Value), dir, "group directory %q is not path-safe", dir.Value)
+				}
+			}
+		}
+	}
+
+	// 用空的 submodule 执行一次模板，以发现不存在的字段
+	if linkName := child(item, "link_name"); linkName != nil {
+		if _, err := (View{LinkName: linkName.Value}).LinkNameOf(SubmoduleConfig{}); err != nil {
+			v.add(path+".link_name", linkName, "invalid link_name template: %v", err)
+		}
+	}
+}
+
+// checkList 检查 mapping 中 key 对应的列表只包含 known 中的值；known 为空时不检查
+func (v *validator) checkList(path string, item *yaml.Node, key, noun string, known []string) {
+	list := child(item, key)
+	if list == nil || len(known) == 0 {
+		return
+	}
+	if list.Kind != yaml.SequenceNode {
+		v.add(path+"."+key, list, "%s must be a list", key)
+		return
+	}
+	for i, value := range list.Content {
+		if !slices.Contains(known, value.Value) {
+			v.add(fmt.Sprintf("%s.%s[%d]", path, key, i), value, "unknown %s %q in %s", noun, value.Value, key)
+		}
+	}
+}
+
+// checkDuplicateNames 检查单个配置文件中重复的 submodule、profile 和 view 名称
+//
+// 必须在合并前检查，合并时同名条目会被折叠成一个。
+func checkDuplicateNames(l *layer) []Problem {
+	var problems []Problem
+	for _, key := range []string{"submodules", "profiles", "views"} {
+		list := child(l.node, key)
+		if list == nil || list.Kind != yaml.SequenceNode {
+			continue
+		}
+
+		seen := map[string]*yaml.Node{}
+		for _, item := range list.Content {
+			name := child(item, "name")
+			if name == nil || name.Value == "" {
+				continue
+			}
+			if first, ok := seen[name.Value]; ok {
+				problems = append(problems, Problem{
+					Origin:  l.origin(name, ""),
+					Message: fmt.Sprintf("duplicate %s name %q (first defined at line %d)", strings.TrimSuffix(key, "s"), name.Value, first.Line),
+				})
+				continue
+			}
+			seen[name.Value] = name
+		}
+	}
+	return problems
+}
+
+// positiveInt 判断节点是否为大于 0 的整数
+func positiveInt(n *yaml.Node) bool {
+	if n == nil {
+		return false
+	}
+	i, err := strconv.Atoi(n.Value)
+	return err == nil && i > 0
+}
+
+// validFilter 判断是否为支持的部分克隆 filter
+func validFilter(filter string) bool {
+	switch filter {
+	case "blob:none", "tree:0":
+		return true
+	}
+	return blobLimitPattern.MatchString(filter)
+}
+
+// validName 判断名称能否安全地用作目录名
+func validName(name string) bool {
+	return namePattern.MatchString(name) && name != "." && name != ".."
+}
+
+// validRepoURL 接受 scp 风格地址、带 scheme 的 URL 和本地路径
+func validRepoURL(repo string) bool {
+	if scpPattern.MatchString(repo) {
+		return true
+	}
+	if filepath.IsAbs(repo) || strings.HasPrefix(repo, "./") || strings.HasPrefix(repo, "../") {
+		return true
+	}
+
+	u, err := url.Parse(repo)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "ssh", "git", "http", "https":
+		return u.Host != "" && strings.Trim(u.Path, "/") != ""
+	case "file":
+		return u.Path != ""
+	}
+	return false
+}
+
+// validRefName 按 git check-ref-format 的规则校验分支名
+func validRefName(ref string) bool {
+	if ref == "" || ref == "@" || strings.HasPrefix(ref, "-") || strings.HasPrefix(ref, "/") ||
+		strings.HasSuffix(ref, "/") || strings.HasSuffix(ref, ".") || strings.HasSuffix(ref, ".lock") ||
+		strings.Contains(ref, "..") || strings.Contains(ref, "@{") || strings.Contains(ref, "//") {
+		return false
+	}
+	for _, r := range ref {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(" ~^:?*[\\", r) {
+			return false
+		}
+	}
+	for _, part := range strings.Split(ref, "/") {
+		if strings.HasPrefix(part, ".") {
+			return false
+		}
+	}
+	return true
+}
+
+// child 返回 mapping 中 key 对应的值节点
+func child(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	if i := mappingIndex(n, key); i >= 0 {
+		return n.Content[i+1]
+	}
+	return nil
+}
+
+// sequenceValues 返回标量列表中的所有值
+func sequenceValues(n *yaml.Node) []string {
+	if n == nil || n.Kind != yaml.SequenceNode {
+		return nil
+	}
+	values := make([]string, 0, len(n.Content))
+	for _, item := range n.Content {
+		values = append(values, item.Value)
+	}
+	return values
+}
+
+// itemPath 返回列表元素的配置路径，与合并时记录来源使用的格式一致
+func itemPath(seq *yaml.Node, i int, path string) string {
+	if isNamedList(seq) {
+		return fmt.Sprintf("%s[%s]", path, mappingValue(seq.Content[i], "name"))
+	}
+	return fmt.Sprintf("%s[%d]", path, i)
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateProblems(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		env   map[string]string
+		want  []string
+	}{
+		{
+			name:  "valid",
+			files: map[string]string{"sm.yaml": testManifest},
+		},
+		{
+			name: "duplicate submodule",
+			files: map[string]string{"sm.yaml": `products: [x]
+submodules:
+  - name: foo
+    repo: git@github.com:org/foo.git
+    type: tools
+    product: x
+  - name: foo
+    repo: git@github.com:org/foo2.git
+    type: tools
+    product: x
+`},
+			want: []string{`sm.yaml:7:11: duplicate submodule name "foo" (first defined at line 3)`},
+		},
+		{
+			name: "duplicate profile and view in local file",
+			files: map[string]string{
+				"sm.yaml": testManifest,
+				"sm.local.yaml": `profiles:
+  - name: p
+    types: [tools]
+  - name: p
+    types: [client]
+views:
+  - name: v
+    group_by: type
+  - name: v
+    group_by: tag
+`,
+			},
+			want: []string{
+				`sm.local.yaml:4:11: duplicate profile name "p" (first defined at line 2)`,
+				`sm.local.yaml:9:11: duplicate view name "v" (first defined at line 7)`,
+			},
+		},
+		{
+			name: "same name in two files is merged",
+			files: map[string]string{
+				"sm.yaml":       testManifest,
+				"sm.local.yaml": "submodules:\n  - name: foo\n    branch: dev\n",
+			},
+		},
+		{
+			name: "missing fields are reported at the entry",
+			files: map[string]string{"sm.yaml": `products: [x]
+submodules:
+  - name: foo
+    type: tools
+`},
+			want: []string{
+				`sm.yaml:3:5: submodule "foo" is missing a repo`,
+				`sm.yaml:3:5: submodule "foo" is missing a product`,
+			},
+		},
+		{
+			name: "unknown values sorted by position",
+			files: map[string]string{"sm.yaml": `products: [x]
+submodules:
+  - name: foo
+    product: w
+    repo: git@github.com:org/foo.git
+    type: daemon
+    strategy: squash
+`},
+			want: []string{
+				`sm.yaml:4:14: unknown product "w" (expected one of: x)`,
+				`sm.yaml:6:11: unknown type "daemon" (expected one of: service, client, specs, tools)`,
+				`sm.yaml:7:15: unknown strategy "squash" (expected one of: rebase, merge, ff-only, fetch)`,
+			},
+		},
+		{
+			name: "local override of a project entry",
+			files: map[string]string{
+				"sm.yaml":       testManifest,
+				"sm.local.yaml": "submodules:\n  - name: foo\n    depth: -1\n",
+			},
+			want: []string{`sm.local.yaml:3:12: depth must be a non-negative integer, got "-1"`},
+		},
+		{
+			name: "short revision on a shallow clone",
+			files: map[string]string{"sm.yaml": testManifest + `    depth: 1
+    revision: 3801e75
+`},
+			want: []string{`sm.yaml:9:15: revision "3801e75" must be a full 40-character SHA when depth or single_branch is set`},
+		},
+		{
+			name: "profile references",
+			files: map[string]string{"sm.yaml": testManifest + `profiles:
+  - name: p
+    repos: [foo, nope]
+    tags: [go, rust]
+`},
+			want: []string{
+				`sm.yaml:10:18: unknown submodule "nope" in repos`,
+				`sm.yaml:11:16: unknown tag "rust" in tags`,
+			},
+		},
+		{
+			name: "view",
+			files: map[string]string{"sm.yaml": testManifest + `views:
+  - name: by-lang
+    group_by: language
+    link_name: "{{.Nickname}}"
+`},
+			want: []string{
+				`sm.yaml:10:15: unknown group_by "language" (expected one of: type, product, tag, owner)`,
+				`sm.yaml:11:16: invalid link_name template: template: link_name:1:2: executing "link_name" at <.Nickname>: can't evaluate field Nickname in type config.linkNameData`,
+			},
+		},
+		{
+			name:  "env value has no position",
+			files: map[string]string{"sm.yaml": testManifest},
+			env:   map[string]string{"SM_CLONE_METHOD": "ftp"},
+			want:  []string{`env (SM_CLONE_METHOD): unknown clone_method "ftp" (expected one of: ssh, https)`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			root := writeFiles(t, tt.files)
+
+			res, err := Resolve(root, Options{SkipValidation: true})
+			if err != nil {
+				t.Fatalf("Resolve: %v", err)
+			}
+			var got []string
+			for _, p := range res.Validate() {
+				got = append(got, p.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("problems:\n got  %q\n want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveRejectsInvalid(t *testing.T) {
+	clearEnv(t)
+	root := writeFiles(t, map[string]string{"sm.yaml": `products: [x]
+submodules:
+  - name: ../foo
+    repo: git@github.com:org/foo.git
+    type: tools
+    product: x
+`})
+
+	_, err := Resolve(root, Options{})
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Resolve error = %v, want *ValidationError", err)
+	}
+	if got := verr.Problems[0].String(); got != `sm.yaml:3:11: name "../foo" is not path-safe (letters, digits, '.', '_' and '-' only)` {
+		t.Errorf("first problem = %s", got)
+	}
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"strings"
+	"text/template"
+)
+
+// KnownGroupKeys 是软链视图支持的分组字段
+var KnownGroupKeys = []string{"type", "product", "tag", "owner"}
+
+// View 定义 sm links 生成的一个软链视图，软链位于 <view>/<group>/<link_name>
+type View struct {
+	Name    string `json:"name" yaml:"name"`
+	GroupBy string `json:"group_by" yaml:"group_by"` // type、product、tag 或 owner
+	// Groups 把分组值映射为目录名，如 service: services；设置后不在其中的分组不生成软链，
+	// 为空时直接使用分组值作为目录名
+	Groups map[string]string `json:"groups,omitempty" yaml:"groups,omitempty"`
+	// LinkName 是软链名称的模板，如 {{.Short}}，为空时使用 submodule 名称
+	LinkName string `json:"link_name,omitempty" yaml:"link_name,omitempty"`
+}
+
+// DefaultViews 返回 manifest 没有定义 views 时使用的 by-type 和 by-product 视图
+func DefaultViews() []View {
+	return []View{
+		{
+			Name:    "by-type",
+			GroupBy: "type",
+			Groups:  map[string]string{"service": "services", "client": "clients", "specs": "specs", "tools": "tools"},
+		},
+		{
+			Name:     "by-product",
+			GroupBy:  "product",
+			LinkName: "{{.Short}}",
+		},
+	}
+}
+
+// LinkViews 返回 sm links 要生成的视图
+func (c *Config) LinkViews() []View {
+	if len(c.Views) > 0 {
+		return c.Views
+	}
+	return DefaultViews()
+}
+
+// GroupsOf 返回 submodule 在视图中所属分组的目录名，按 tag 或 owner 分组时
+// 可能属于多个分组，不属于任何分组时返回空
+func (v View) GroupsOf(sm SubmoduleConfig) []string {
+	var values []string
+	switch v.GroupBy {
+	case "type":
+		values = []string{sm.Type}
+	case "product":
+		values = []string{sm.Product}
+	case "tag":
+		values = sm.Tags
+	case "owner":
+		values = sm.Owners
+	}
+
+	var dirs []string
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		if len(v.Groups) == 0 {
+			dirs = append(dirs, value)
+		} else if dir, ok := v.Groups[value]; ok {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// linkNameData 是软链名称模板可以使用的字段
+type linkNameData struct {
+	SubmoduleConfig
+	// Short 是 alias，未设置时为去掉 "<product>-" 前缀的名称
+	Short string
+}
+
+var linkNameFuncs = template.FuncMap{
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+}
+
+func parseLinkName(text string) (*template.Template, error) {
+	return template.New("link_name").Funcs(linkNameFuncs).Option("missingkey=error").Parse(text)
+}
+
+// LinkNameOf 返回 submodule 在视图中的软链名称
+func (v View) LinkNameOf(sm SubmoduleConfig) (string, error) {
+	if v.LinkName == "" {
+		return sm.Name, nil
+	}
+	tmpl, err := parseLinkName(v.LinkName)
+	if err != nil {
+		return "", err
+	}
+
+	data := linkNameData{SubmoduleConfig: sm, Short: sm.Alias}
+	if data.Short == "" {
+		data.Short = strings.TrimPrefix(sm.Name, sm.Product+"-")
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
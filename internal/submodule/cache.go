@@ -0,0 +1,245 @@
+package submodule
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/inspirai-store/inspirai-devkit/internal/config"
+)
+
+// mirrorUsedFile 是 mirror 中记录最近一次被 sm 使用的时间的文件（以修改时间为准）
+const mirrorUsedFile = "sm-last-used"
+
+// scpRepo 匹配 git@github.com:org/repo.git 形式的地址
+var scpRepo = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`)
+
+// mirrorKey 把仓库地址转换为缓存中的相对路径（host/org/repo），同一仓库的 ssh 和
+// https 地址得到相同的结果，本地路径归到 file/ 下；无法得到安全的相对路径时返回空字符串
+func mirrorKey(repo string) string {
+	repo = strings.TrimSuffix(strings.TrimRight(repo, "/"), ".git")
+
+	var key string
+	if u, err := url.Parse(repo); err == nil && u.Scheme == "file" {
+		key = path.Join("file", u.Path)
+	} else if err == nil && u.Scheme != "" && u.Host != "" {
+		key = path.Join(strings.ToLower(u.Hostname()), u.Path)
+	} else if m := scpRepo.FindStringSubmatch(repo); m != nil && !filepath.IsAbs(repo) {
+		key = path.Join(strings.ToLower(m[1]), m[2])
+	} else {
+		abs, err := filepath.Abs(repo)
+		if err != nil {
+			return ""
+		}
+		key = path.Join("file", filepath.ToSlash(abs))
+	}
+
+	if !filepath.IsLocal(key) || !strings.Contains(key, "/") {
+		return ""
+	}
+	return key
+}
+
+// mirrorPath 返回仓库在缓存中的 bare mirror 路径，未配置缓存或地址无法识别时返回空字符串
+func mirrorPath(cfg *config.Config, repo string) string {
+	dir := cfg.MirrorDir()
+	key := mirrorKey(repo)
+	if dir == "" || key == "" {
+		return ""
+	}
+	return filepath.Join(dir, filepath.FromSlash(key)+".git")
+}
+
+// mirrorOrigin 返回 mirror 的 origin 地址
+func mirrorOrigin(mirror string) (string, error) {
+	out, err := exec.Command("git", "--git-dir", mirror, "remote", "get-url", "origin").Output()
+	if err != nil {
+		return "", fmt.Errorf("%s has no origin remote", mirror)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// checkMirror 确认 mirror 的 origin 与 repo 是同一个仓库
+func checkMirror(mirror, repo string) error {
+	origin, err := mirrorOrigin(mirror)
+	if err != nil {
+		return err
+	}
+	if mirrorKey(origin) != mirrorKey(repo) {
+		return fmt.Errorf("%s is a mirror of %s, not %s", mirror, origin, repo)
+	}
+	return nil
+}
+
+// usableMirror 返回 init 可以借用对象的 mirror，不存在或属于其他仓库时返回空字符串
+func usableMirror(cfg *config.Config, repo string) string {
+	mirror := mirrorPath(cfg, repo)
+	if mirror == "" {
+		return ""
+	}
+	if _, err := os.Stat(mirror); err != nil || checkMirror(mirror, repo) != nil {
+		return ""
+	}
+	touchMirror(mirror)
+	return mirror
+}
+
+// touchMirror 记录 mirror 被使用的时间，供 cache prune --unused-days 判断
+func touchMirror(mirror string) {
+	marker := filepath.Join(mirror, mirrorUsedFile)
+	now := time.Now()
+	if err := os.Chtimes(marker, now, now); os.IsNotExist(err) {
+		os.WriteFile(marker, nil, 0644)
+	}
+}
+
+// CacheUpdate 创建或更新每个 submodule 的 bare mirror，r 接收每个仓库的进度，为 nil 时不输出
+func CacheUpdate(cfg *config.Config, r Reporter) (*Result, error) {
+	r = orSilent(r)
+	dir := cfg.MirrorDir()
+	if dir == "" {
+		return nil, fmt.Errorf("no cache_dir configured (set cache_dir in sm.local.yaml, SM_CACHE_DIR or --cache-dir)")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache dir: %w", err)
+	}
+
+	result := NewResult("updated")
+	// 同一仓库的多个 submodule 共用一个 mirror，只更新一次
+	updated := map[string]string{}
+	for _, sm := range cfg.Submodules {
+		r.Start(sm.Name)
+		mirror := mirrorPath(cfg, sm.Repo)
+
+		var out bytes.Buffer
+		if mirror == "" {
+			result.Skip(sm.Name, "repo URL cannot be mapped to a mirror")
+		} else if other, ok := updated[mirror]; ok {
+			result.Skip(sm.Name, "shares the mirror of "+other)
+		} else if err := updateMirror(mirror, config.ConvertRepoURL(sm.Repo, cfg.CloneMethod), sm.Name, r, &out); err != nil {
+			result.Fail(sm.Name, err)
+		} else {
+			updated[mirror] = sm.Name
+			result.Done(sm.Name)
+		}
+		r.Finish(result.Repos[len(result.Repos)-1], out.Bytes())
+	}
+
+	return result, nil
+}
+
+// updateMirror 创建或更新 repoURL 的 mirror，已有的 mirror 属于其他仓库时返回错误
+func updateMirror(mirror, repoURL, name string, r Reporter, out *bytes.Buffer) error {
+	var cmd *exec.Cmd
+	if _, err := os.Stat(mirror); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(mirror), 0755); err != nil {
+			return err
+		}
+		r.Progress(name, "mirroring")
+		cmd = exec.Command("git", "clone", "--mirror", repoURL, mirror)
+	} else {
+		if err := checkMirror(mirror, repoURL); err != nil {
+			return err
+		}
+		r.Progress(name, "fetching")
+		cmd = exec.Command("git", "-C", mirror, "remote", "update", "--prune")
+	}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+	touchMirror(mirror)
+	return nil
+}
+
+// PruneOptions 控制 CachePrune 删除哪些 mirror
+type PruneOptions struct {
+	DryRun bool
+	// UnusedFor 大于 0 时，同时删除超过这段时间没有被 sm 使用过的 mirror
+	UnusedFor time.Duration
+}
+
+// PrunedMirror 是 CachePrune 删除（或 dry-run 时将要删除）的一个 mirror
+type PrunedMirror struct {
+	Path   string `json:"path" yaml:"path"`
+	Repo   string `json:"repo" yaml:"repo"`
+	Reason string `json:"reason" yaml:"reason"`
+}
+
+// CachePrune 删除缓存中可以确定已经过时的 mirror：不在其 origin 地址对应位置上的
+// （sm 不会再使用它们），以及设置了 UnusedFor 时超过这段时间没有被使用过的。
+// 缓存可能被多个工作区共用，所以不属于当前配置的 mirror 不会仅因此被删除；
+// 不是由 git clone --mirror 创建的目录和文件一律不动
+func CachePrune(cfg *config.Config, opts PruneOptions) ([]PrunedMirror, error) {
+	dir := cfg.MirrorDir()
+	if dir == "" {
+		return nil, fmt.Errorf("no cache_dir configured (set cache_dir in sm.local.yaml, SM_CACHE_DIR or --cache-dir)")
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	inUse := map[string]bool{}
+	for _, sm := range cfg.Submodules {
+		inUse[mirrorPath(cfg, sm.Repo)] = true
+	}
+
+	pruned := []PrunedMirror{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || p == dir || !strings.HasSuffix(d.Name(), ".git") {
+			return nil
+		}
+		// 不进入任何 *.git 目录，只有 mirror 本身才会被考虑删除
+		if !isMirror(p) || inUse[p] {
+			return filepath.SkipDir
+		}
+
+		origin, err := mirrorOrigin(p)
+		if err != nil {
+			return filepath.SkipDir
+		}
+		var reason string
+		if expected := mirrorPath(cfg, origin); expected != p {
+			reason = "not at the location of its repo URL"
+		} else if opts.UnusedFor > 0 {
+			if info, err := os.Stat(filepath.Join(p, mirrorUsedFile)); err == nil && time.Since(info.ModTime()) > opts.UnusedFor {
+				reason = "unused since " + info.ModTime().Format(time.DateOnly)
+			}
+		}
+		if reason == "" {
+			return filepath.SkipDir
+		}
+
+		if !opts.DryRun {
+			if err := os.RemoveAll(p); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", p, err)
+			}
+		}
+		pruned = append(pruned, PrunedMirror{Path: p, Repo: origin, Reason: reason})
+		return filepath.SkipDir
+	})
+	return pruned, err
+}
+
+// isMirror 判断 path 是否是 git clone --mirror 创建的 bare 仓库；使用 --git-dir
+// 避免 git 向上找到缓存目录所在的其他仓库
+func isMirror(path string) bool {
+	out, err := exec.Command("git", "--git-dir", path, "config", "--local", "--get", "remote.origin.mirror").Output()
+	if err != nil || strings.TrimSpace(string(out)) != "true" {
+		return false
+	}
+	out, err = exec.Command("git", "--git-dir", path, "rev-parse", "--is-bare-repository").Output()
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
@@ -0,0 +1,171 @@
+package submodule
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/inspirai-store/inspirai-devkit/internal/config"
+)
+
+func TestMirrorKey(t *testing.T) {
+	tests := map[string]string{
+		"git@github.com:org/repo.git":       "github.com/org/repo",
+		"https://github.com/org/repo.git":   "github.com/org/repo",
+		"https://GitHub.com/org/repo/":      "github.com/org/repo",
+		"ssh://git@github.com/org/repo.git": "github.com/org/repo",
+		"git@github.com:fork/repo.git":      "github.com/fork/repo",
+		"/srv/git/repo.git":                 "file/srv/git/repo",
+		"file:///srv/git/repo.git":          "file/srv/git/repo",
+		"https://github.com/../../etc":      "",
+	}
+	for repo, want := range tests {
+		if got := mirrorKey(repo); got != want {
+			t.Errorf("mirrorKey(%q) = %q, want %q", repo, got, want)
+		}
+	}
+}
+
+func TestCacheUpdateAndClone(t *testing.T) {
+	isolateGit(t)
+	bare, work := newOrigin(t)
+	fork := filepath.Join(t.TempDir(), "fork.git")
+	git(t, work, "clone", "-q", "--bare", bare, fork)
+
+	cfg := &config.Config{
+		SubmodulesDir: ".submodules",
+		CacheDir:      t.TempDir(),
+		Submodules: []config.SubmoduleConfig{
+			{Name: "alpha", Repo: bare},
+			{Name: "beta", Repo: bare},
+			{Name: "gamma", Repo: fork},
+		},
+	}
+
+	result, err := CacheUpdate(cfg, nil)
+	if err != nil {
+		t.Fatalf("CacheUpdate: %v", err)
+	}
+	want := []Outcome{OutcomeDone, OutcomeSkipped, OutcomeDone}
+	for i, repo := range result.Repos {
+		if repo.Outcome != want[i] {
+			t.Errorf("%s: outcome = %s (%s), want %s", repo.Name, repo.Outcome, repo.Detail, want[i])
+		}
+	}
+
+	// 同名仓库的 fork 使用自己的 mirror
+	mirror, forkMirror := mirrorPath(cfg, bare), mirrorPath(cfg, fork)
+	if mirror == forkMirror {
+		t.Fatalf("fork shares the mirror %s", mirror)
+	}
+	if got := git(t, mirror, "remote", "get-url", "origin"); got != bare {
+		t.Errorf("mirror origin = %s, want %s", got, bare)
+	}
+
+	// 再次更新会获取新提交
+	head := commitFile(t, work, "CHANGELOG", "two\n")
+	git(t, work, "push", "-q", "origin", "HEAD:main")
+	if result, _ := CacheUpdate(cfg, nil); result.Err() != nil {
+		t.Fatalf("CacheUpdate: %v", result.Err())
+	}
+	if got := git(t, mirror, "rev-parse", "main"); got != head {
+		t.Errorf("mirror main = %s, want %s", got, head)
+	}
+
+	// init 借用 mirror 的对象，clone 完成后不再依赖缓存
+	root := t.TempDir()
+	repo, out := cloneSubmodule(cfg, root, cfg.Submodules[0], orSilent(nil))
+	if repo.Outcome != OutcomeDone {
+		t.Fatalf("clone: %s (%s)\n%s", repo.Outcome, repo.Detail, out)
+	}
+	if got := git(t, filepath.Join(root, "alpha"), "rev-parse", "HEAD"); got != head {
+		t.Errorf("HEAD = %s, want %s", got, head)
+	}
+	if _, err := os.Stat(filepath.Join(root, "alpha", ".git", "objects", "info", "alternates")); !os.IsNotExist(err) {
+		t.Errorf("clone still references the mirror (err = %v)", err)
+	}
+
+	// 位置上的 mirror 属于其他仓库时不被借用，也不被更新
+	if err := os.RemoveAll(forkMirror); err != nil {
+		t.Fatal(err)
+	}
+	git(t, work, "clone", "-q", "--mirror", bare, forkMirror)
+	if got := usableMirror(cfg, fork); got != "" {
+		t.Errorf("usableMirror(fork) = %s, want none", got)
+	}
+	if got := usableMirror(cfg, bare); got != mirror {
+		t.Errorf("usableMirror(origin) = %s, want %s", got, mirror)
+	}
+	result, _ = CacheUpdate(cfg, nil)
+	if gamma := result.Repos[2]; gamma.Outcome != OutcomeFailed {
+		t.Errorf("gamma: outcome = %s, want failed", gamma.Outcome)
+	}
+}
+
+func TestCachePrune(t *testing.T) {
+	isolateGit(t)
+	bare, work := newOrigin(t)
+	other, _ := newOrigin(t)
+	cfg := &config.Config{
+		CacheDir:   t.TempDir(),
+		Submodules: []config.SubmoduleConfig{{Name: "alpha", Repo: bare}},
+	}
+	dir := cfg.CacheDir
+
+	inUse := mirrorPath(cfg, bare)
+	foreign := mirrorPath(cfg, other)
+	misplaced := filepath.Join(dir, "alpha.git")
+	git(t, work, "clone", "-q", "--mirror", bare, inUse)
+	git(t, work, "clone", "-q", "--mirror", other, foreign)
+	git(t, work, "clone", "-q", "--mirror", bare, misplaced)
+	// 不是 mirror 的内容不会被删除
+	git(t, work, "init", "-q", "--bare", filepath.Join(dir, "plain.git"))
+	writeFile(t, filepath.Join(dir, "notes.git"), "README", "mine\n")
+
+	// 其他工作区的 mirror 很久没有被使用
+	touchMirror(foreign)
+	old := time.Now().Add(-100 * 24 * time.Hour)
+	if err := os.Chtimes(filepath.Join(foreign, mirrorUsedFile), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	prune := func(opts PruneOptions) []string {
+		t.Helper()
+		pruned, err := CachePrune(cfg, opts)
+		if err != nil {
+			t.Fatalf("CachePrune: %v", err)
+		}
+		var paths []string
+		for _, m := range pruned {
+			paths = append(paths, m.Path)
+		}
+		sort.Strings(paths)
+		return paths
+	}
+	exists := func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}
+
+	if got := prune(PruneOptions{DryRun: true, UnusedFor: 90 * 24 * time.Hour}); len(got) != 2 || !exists(misplaced) || !exists(foreign) {
+		t.Fatalf("dry run = %v, want 2 mirrors and nothing removed", got)
+	}
+
+	if got := prune(PruneOptions{}); len(got) != 1 || got[0] != misplaced {
+		t.Errorf("pruned %v, want only %s", got, misplaced)
+	}
+	if !exists(foreign) {
+		t.Error("the mirror of another workspace was removed")
+	}
+
+	if got := prune(PruneOptions{UnusedFor: 90 * 24 * time.Hour}); len(got) != 1 || got[0] != foreign {
+		t.Errorf("pruned %v, want only %s", got, foreign)
+	}
+	for _, path := range []string{inUse, filepath.Join(dir, "plain.git"), filepath.Join(dir, "notes.git", "README")} {
+		if !exists(path) {
+			t.Errorf("%s was removed", path)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package submodule
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// gitOutput 在仓库中执行 git 命令并返回去掉首尾空白的标准输出
+func gitOutput(path string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", path}, args...)...)
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// hasCommit 判断仓库中是否存在指定的提交
+func hasCommit(path, commit string) bool {
+	_, err := gitOutput(path, "cat-file", "-e", commit+"^{commit}")
+	return err == nil
+}
+
+// runQuiet 执行 git 命令，只在失败时返回 git 的错误输出
+func runQuiet(path string, args ...string) error {
+	cmd := exec.Command("git", append([]string{"-C", path}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// inProgress 返回仓库中未完成的操作（rebase、merge、cherry-pick、revert），没有时返回空字符串
+func inProgress(path string) string {
+	markers := []struct{ file, op string }{
+		{"rebase-merge", "rebase"},
+		{"rebase-apply", "rebase"},
+		{"MERGE_HEAD", "merge"},
+		{"CHERRY_PICK_HEAD", "cherry-pick"},
+		{"REVERT_HEAD", "revert"},
+	}
+	for _, m := range markers {
+		p, err := gitOutput(path, "rev-parse", "--git-path", m.file)
+		if err != nil {
+			return ""
+		}
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(path, p)
+		}
+		if _, err := os.Stat(p); err == nil {
+			return m.op
+		}
+	}
+	return ""
+}
@@ -0,0 +1,68 @@
+package submodule
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// isolateGit 让测试中的 git 不读取用户和系统配置，并使用固定的作者
+func isolateGit(t *testing.T) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(home, ".gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "sm test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "sm@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "sm test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "sm@example.com")
+}
+
+// git 在 dir 中执行 git 命令，失败时结束测试，返回去掉首尾空白的输出
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// writeFile 写入 dir 下的文件，自动创建上级目录
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// commitFile 写入文件并提交，返回新提交的 SHA
+func commitFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	writeFile(t, dir, name, content)
+	git(t, dir, "add", name)
+	git(t, dir, "commit", "-q", "-m", "update "+name)
+	return git(t, dir, "rev-parse", "HEAD")
+}
+
+// newOrigin 创建一个带初始提交的 bare 仓库，返回其路径和用于继续推送提交的工作副本
+func newOrigin(t *testing.T) (bare, work string) {
+	t.Helper()
+	dir := t.TempDir()
+	bare = filepath.Join(dir, "origin.git")
+	work = filepath.Join(dir, "work")
+	git(t, dir, "init", "-q", "--bare", "-b", "main", bare)
+	git(t, dir, "clone", "-q", bare, work)
+	commitFile(t, work, "README", "one\n")
+	git(t, work, "push", "-q", "origin", "HEAD:main")
+	return bare, work
+}
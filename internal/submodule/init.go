@@ -1,143 +1,120 @@
 package submodule
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 
-	"github.com/fatih/color"
 	"github.com/inspirai-store/inspirai-devkit/internal/config"
 )
 
-// Init 初始化所有 submodule 并创建软链
-func Init(cfg *config.Config, root string) error {
+// InitOptions 控制 Init 的行为
+type InitOptions struct {
+	// Jobs 是并发 clone 的数量，小于等于 1 时串行执行
+	Jobs int
+	// FailFast 为 true 时遇到第一个失败就不再开始新的 clone
+	FailFast bool
+	// Reporter 接收每个仓库的进度，为 nil 时不输出
+	Reporter Reporter
+	// KeepLinks 是不在本次 clone 范围内的 submodule，创建软链时保留它们已有的软链
+	KeepLinks []string
+}
+
+// Init 初始化所有 submodule 并创建软链，返回每个仓库的 clone 结果
+func Init(cfg *config.Config, root string, opts InitOptions) (*Result, error) {
 	submodulesDir := filepath.Join(root, cfg.SubmodulesDir)
 
 	// 确保 .submodules 目录存在
 	if err := os.MkdirAll(submodulesDir, 0755); err != nil {
-		return fmt.Errorf("failed to create submodules dir: %w", err)
+		return nil, fmt.Errorf("failed to create submodules dir: %w", err)
 	}
 
-	// 读取 git clone 方式
-	gitMethod := config.GetGitCloneMethod(root)
-
-	// 克隆每个 submodule
-	for _, sm := range cfg.Submodules {
-		smPath := filepath.Join(submodulesDir, sm.Name)
-		if _, err := os.Stat(smPath); err == nil {
-			color.Yellow("  [skip] %s already exists", sm.Name)
-			continue
-		}
-
-		repoURL := config.ConvertRepoURL(sm.Repo, gitMethod)
-		color.Cyan("  [clone] %s", sm.Name)
-		cmd := exec.Command("git", "clone", repoURL, smPath)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err != nil {
-			color.Red("  [error] failed to clone %s: %v", sm.Name, err)
-			continue
-		}
-		color.Green("  [done] %s", sm.Name)
-	}
+	r := orSilent(opts.Reporter)
+	result := runPool(cfg.Submodules, opts.Jobs, opts.FailFast, "cloned", func(sm config.SubmoduleConfig) RepoResult {
+		r.Start(sm.Name)
+		repo, output := cloneSubmodule(cfg, submodulesDir, sm, r)
+		r.Finish(repo, output)
+		return repo
+	})
 
 	// 创建软链
-	if err := CreateLinks(cfg, root); err != nil {
-		return err
+	if _, err := CreateLinks(cfg, root, LinkOptions{Keep: opts.KeepLinks, Reporter: r}); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return result, nil
 }
 
-// CreateLinks 创建两种视图的软链
-func CreateLinks(cfg *config.Config, root string) error {
-	color.Cyan("\nCreating symlinks...")
-
-	// by-type 视图
-	typeMap := map[string][]string{
-		"services": {},
-		"clients":  {},
-		"specs":    {},
-		"tools":    {},
-	}
-	typeMapping := map[string]string{
-		"service": "services",
-		"client":  "clients",
-		"specs":   "specs",
-		"tools":   "tools",
+// cloneSubmodule clone 单个 submodule，返回结果和 git 的输出
+func cloneSubmodule(cfg *config.Config, submodulesDir string, sm config.SubmoduleConfig, r Reporter) (RepoResult, []byte) {
+	smPath := filepath.Join(submodulesDir, sm.Name)
+	if _, err := os.Stat(smPath); err == nil {
+		return RepoResult{Name: sm.Name, Outcome: OutcomeSkipped, Detail: "already exists"}, nil
 	}
 
-	for _, sm := range cfg.Submodules {
-		if dir, ok := typeMapping[sm.Type]; ok {
-			typeMap[dir] = append(typeMap[dir], sm.Name)
-		}
+	type step struct {
+		phase string
+		args  []string
 	}
 
-	for dir, names := range typeMap {
-		dirPath := filepath.Join(root, "by-type", dir)
-		if err := os.MkdirAll(dirPath, 0755); err != nil {
-			return err
-		}
-		for _, name := range names {
-			linkPath := filepath.Join(dirPath, name)
-			target := filepath.Join("..", "..", cfg.SubmodulesDir, name)
-			createSymlink(linkPath, target)
-		}
+	repoURL := config.ConvertRepoURL(sm.Repo, cfg.CloneMethod)
+	steps := []step{{"cloning", cloneArgs(sm, repoURL, smPath, usableMirror(cfg, sm.Repo))}}
+	if len(sm.Sparse) > 0 {
+		steps = append(steps, step{"sparse-checkout", append([]string{"-C", smPath, "sparse-checkout", "set"}, sm.Sparse...)})
 	}
-
-	// by-product 视图
-	productMap := map[string]map[string]string{}
-	for _, sm := range cfg.Submodules {
-		if productMap[sm.Product] == nil {
-			productMap[sm.Product] = map[string]string{}
+	if sm.Revision != "" {
+		if sm.Depth > 0 || sm.SingleBranch {
+			// 浅克隆或单分支时 revision 可能不在已获取的历史中
+			steps = append(steps, step{"fetching " + sm.Revision, []string{"-C", smPath, "fetch", "origin", sm.Revision}})
 		}
-		// 使用短名称（去掉产品前缀）
-		shortName := getShortName(sm.Name, sm.Product)
-		productMap[sm.Product][shortName] = sm.Name
+		steps = append(steps, step{"checking out " + sm.Revision, []string{"-C", smPath, "-c", "advice.detachedHead=false", "checkout", "--detach", sm.Revision}})
 	}
 
-	for product, items := range productMap {
-		dirPath := filepath.Join(root, "by-product", product)
-		if err := os.MkdirAll(dirPath, 0755); err != nil {
-			return err
-		}
-		for shortName, fullName := range items {
-			linkPath := filepath.Join(dirPath, shortName)
-			target := filepath.Join("..", "..", cfg.SubmodulesDir, fullName)
-			createSymlink(linkPath, target)
+	var out bytes.Buffer
+	for _, step := range steps {
+		r.Progress(sm.Name, step.phase)
+		cmd := exec.Command("git", step.args...)
+		cmd.Stdout = &out
+		cmd.Stderr = &out
+		if err := cmd.Run(); err != nil {
+			// 删除未完成的 checkout，否则下次 init 会把它当作已经存在而跳过
+			if rmErr := os.RemoveAll(smPath); rmErr != nil {
+				err = fmt.Errorf("%w (failed to remove %s: %v)", err, smPath, rmErr)
+			}
+			return RepoResult{Name: sm.Name, Outcome: OutcomeFailed, Detail: err.Error(), Err: err}, out.Bytes()
 		}
 	}
-
-	color.Green("Symlinks created successfully")
-	return nil
+	return RepoResult{Name: sm.Name, Outcome: OutcomeDone}, out.Bytes()
 }
 
-func createSymlink(linkPath, target string) {
-	// 如果已存在，先删除
-	if _, err := os.Lstat(linkPath); err == nil {
-		os.Remove(linkPath)
+// cloneArgs 把 submodule 的 clone 选项转换为 git clone 参数；mirror 不为空时
+// 从本地缓存借用对象，clone 完成后再断开与缓存的关联
+func cloneArgs(sm config.SubmoduleConfig, repoURL, path, mirror string) []string {
+	args := []string{"-c", "advice.detachedHead=false", "clone"}
+	if mirror != "" {
+		args = append(args, "--reference-if-able", mirror, "--dissociate")
 	}
-
-	if err := os.Symlink(target, linkPath); err != nil {
-		color.Red("  [error] %s: %v", linkPath, err)
-	} else {
-		color.Green("  [link] %s -> %s", linkPath, target)
+	if sm.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(sm.Depth))
 	}
-}
-
-func getShortName(name, product string) string {
-	// lingbo-desktop -> desktop
-	// inspirai-user -> user
-	// skill-market -> skill-market (independent)
-	prefixes := map[string]string{
-		"lingbo":   "lingbo-",
-		"inspirai": "inspirai-",
+	if sm.Filter != "" {
+		args = append(args, "--filter="+sm.Filter)
 	}
-	if prefix, ok := prefixes[product]; ok {
-		if len(name) > len(prefix) && name[:len(prefix)] == prefix {
-			return name[len(prefix):]
-		}
+	if sm.SingleBranch {
+		args = append(args, "--single-branch")
+	}
+	// --branch 同时接受分支名和 tag，tag 会直接检出为 detached HEAD
+	if sm.Tag != "" {
+		args = append(args, "--branch", sm.Tag)
+	} else if sm.Branch != "" {
+		args = append(args, "--branch", sm.Branch)
+	}
+	if len(sm.Sparse) > 0 {
+		// 先只检出根目录文件，随后由 sparse-checkout set 补充目录
+		args = append(args, "--sparse")
 	}
-	return name
+	return append(args, repoURL, path)
 }
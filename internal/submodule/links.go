@@ -0,0 +1,392 @@
+package submodule
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/inspirai-store/inspirai-devkit/internal/config"
+)
+
+// LinkAction 是 CreateLinks 对一个软链的处理方式
+type LinkAction string
+
+const (
+	LinkCreate    LinkAction = "create"    // 新建，或原有软链指向了其他目标
+	LinkUnchanged LinkAction = "unchanged" // 已经指向正确的目标
+	LinkRemove    LinkAction = "remove"    // sm 创建的软链已不属于任何视图
+)
+
+// Link 是视图中指向 submodule 的一个软链
+type Link struct {
+	Path   string     `json:"path" yaml:"path"`
+	Repo   string     `json:"repo" yaml:"repo"`
+	Target string     `json:"target" yaml:"target"`
+	Action LinkAction `json:"action" yaml:"action"`
+	Error  string     `json:"error,omitempty" yaml:"error,omitempty"` // 处理失败的原因
+}
+
+// LinkOptions 控制 CreateLinks 的行为
+type LinkOptions struct {
+	// DryRun 为 true 时只返回计划的创建和删除，不修改文件
+	DryRun bool
+	// Keep 是不在本次选择中的 submodule，它们的软链不会被当作过期清理
+	Keep []string
+	// Reporter 接收每个软链的创建和删除，为 nil 时不输出
+	Reporter Reporter
+}
+
+// viewLink 是某个视图中应当存在的软链
+type viewLink struct {
+	repo   string // submodule 名称
+	path   string // 软链的绝对路径
+	target string // 相对软链所在目录的目标
+}
+
+// planLinks 按配置的视图列出应当存在的分组目录和软链
+//
+// 软链名称无效或同一目录下有两个 submodule 使用相同名称时返回错误。
+func planLinks(cfg *config.Config, root string) ([]string, []viewLink, error) {
+	var dirs []string
+	var links []viewLink
+	owners := map[string]string{}
+
+	for _, view := range cfg.LinkViews() {
+		viewDir := filepath.Join(root, view.Name)
+		// 固定的分组即使没有 submodule 也创建目录
+		for _, dir := range view.Groups {
+			dirs = append(dirs, filepath.Join(viewDir, dir))
+		}
+
+		for _, sm := range cfg.Submodules {
+			name, err := view.LinkNameOf(sm)
+			if err != nil {
+				return nil, nil, fmt.Errorf("view %s: %s: %w", view.Name, sm.Name, err)
+			}
+			if name == "" || name == "." || name == ".." || name != filepath.Base(name) {
+				return nil, nil, fmt.Errorf("view %s: invalid link name %q for %s", view.Name, name, sm.Name)
+			}
+
+			for _, group := range view.GroupsOf(sm) {
+				groupDir := filepath.Join(viewDir, group)
+				linkPath := filepath.Join(groupDir, name)
+				if other, ok := owners[linkPath]; ok {
+					return nil, nil, fmt.Errorf("view %s: %s and %s both link to %s/%s", view.Name, other, sm.Name, group, name)
+				}
+				owners[linkPath] = sm.Name
+
+				dirs = append(dirs, groupDir)
+				links = append(links, viewLink{
+					repo:   sm.Name,
+					path:   linkPath,
+					target: filepath.Join("..", "..", cfg.SubmodulesDir, sm.Name),
+				})
+			}
+		}
+	}
+
+	slices.Sort(dirs)
+	slices.SortFunc(links, func(a, b viewLink) int { return strings.Compare(a.path, b.path) })
+	return slices.Compact(dirs), links, nil
+}
+
+// CreateLinks 按配置的视图创建软链，并删除 sm 以前创建、现在已不再需要的软链
+//
+// sm 创建的软链记录在本地状态中；视图中已有的同名文件或目录不会被覆盖，
+// 而是报告为错误。返回按路径排序的软链列表，每个软链的创建和删除作为对应仓库的
+// 进展报告给 r。
+func CreateLinks(cfg *config.Config, root string, opts LinkOptions) ([]Link, error) {
+	r := orSilent(opts.Reporter)
+	if opts.DryRun {
+		r = Silent
+	}
+
+	dirs, planned, err := planLinks(cfg, root)
+	if err != nil {
+		return nil, err
+	}
+	state, err := config.LoadState(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.DryRun {
+		for _, dir := range dirs {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	links := make([]Link, 0, len(planned))
+	owned := map[string]string{}
+	for _, p := range planned {
+		rel := linkKey(root, p.path)
+		l := Link{Path: p.path, Repo: p.repo, Target: p.target, Action: LinkCreate}
+		if info, err := os.Lstat(p.path); err == nil {
+			if info.Mode()&os.ModeSymlink == 0 {
+				l.Error = "exists and is not a symlink"
+			} else if dest, _ := os.Readlink(p.path); dest == p.target {
+				l.Action = LinkUnchanged
+			}
+		}
+		if l.Error == "" && l.Action == LinkCreate && !opts.DryRun {
+			if err := replaceSymlink(p.path, p.target); err != nil {
+				l.Error = err.Error()
+			}
+		}
+
+		switch {
+		case l.Error != "":
+			r.Error(p.repo, fmt.Errorf("%s: %s", rel, l.Error))
+		case l.Action == LinkCreate:
+			r.Progress(p.repo, fmt.Sprintf("link %s -> %s", rel, p.target))
+		}
+		if l.Error == "" {
+			owned[rel] = p.repo
+		}
+		links = append(links, l)
+	}
+
+	for rel, repo := range state.Links {
+		if _, ok := owned[rel]; ok {
+			continue
+		}
+		if slices.Contains(opts.Keep, repo) {
+			owned[rel] = repo
+			continue
+		}
+
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		info, err := os.Lstat(path)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			continue // 已被删除或替换成了其他文件，不再由 sm 管理
+		}
+
+		l := Link{Path: path, Repo: repo, Action: LinkRemove}
+		l.Target, _ = os.Readlink(path)
+		if !opts.DryRun {
+			if err := os.Remove(path); err != nil {
+				l.Error = err.Error()
+				owned[rel] = repo
+			} else {
+				removeEmptyDirs(root, filepath.Dir(path), dirs)
+			}
+		}
+
+		if l.Error != "" {
+			r.Error(repo, fmt.Errorf("%s: %s", rel, l.Error))
+		} else {
+			r.Progress(repo, "unlink "+rel)
+		}
+		links = append(links, l)
+	}
+
+	slices.SortFunc(links, func(a, b Link) int { return strings.Compare(a.Path, b.Path) })
+
+	if opts.DryRun {
+		return links, nil
+	}
+	state.Links = owned
+	if err := config.SaveState(root, state); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// linkKey 返回软链在本地状态中的 key，即相对项目根目录的路径
+func linkKey(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		rel = path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// replaceSymlink 创建软链，已有的软链会被替换
+func replaceSymlink(linkPath, target string) error {
+	if info, err := os.Lstat(linkPath); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if err := os.Remove(linkPath); err != nil {
+			return err
+		}
+	}
+	return os.Symlink(target, linkPath)
+}
+
+// removeEmptyDirs 从 dir 开始向上删除清理软链后变空的分组和视图目录，
+// 视图中仍然需要的目录 keep 会保留
+func removeEmptyDirs(root, dir string, keep []string) {
+	for dir != root && strings.HasPrefix(dir, root+string(filepath.Separator)) {
+		if slices.Contains(keep, dir) || os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// PrintLinks 打印 CreateLinks 的结果；dryRun 时列出计划的创建和删除
+func PrintLinks(root string, links []Link, dryRun bool) {
+	counts := map[LinkAction]int{}
+	for _, l := range links {
+		rel := linkKey(root, l.Path)
+		switch {
+		case l.Error != "":
+			// 实际执行时错误已经通过 Reporter 输出
+			if dryRun {
+				color.Red("  [error] %s: %s", rel, l.Error)
+			}
+			continue
+		case !dryRun || l.Action == LinkUnchanged:
+		case l.Action == LinkCreate:
+			color.Green("  [create] %s -> %s", rel, l.Target)
+		case l.Action == LinkRemove:
+			color.Yellow("  [remove] %s", rel)
+		}
+		counts[l.Action]++
+	}
+
+	summary := fmt.Sprintf("%d created, %d removed, %d unchanged", counts[LinkCreate], counts[LinkRemove], counts[LinkUnchanged])
+	if dryRun {
+		summary = fmt.Sprintf("%d to create, %d to remove, %d unchanged (dry run)", counts[LinkCreate], counts[LinkRemove], counts[LinkUnchanged])
+	}
+	color.Green(summary)
+}
+
+// LinksErr 在有软链无法创建或删除时返回错误
+func LinksErr(links []Link) error {
+	var failed []string
+	for _, l := range links {
+		if l.Error != "" {
+			failed = append(failed, l.Path)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d symlinks could not be updated", len(failed))
+	}
+	return nil
+}
+
+// LinkProblemKind 是 CheckLinks 发现的问题类型
+type LinkProblemKind string
+
+const (
+	LinkDangling    LinkProblemKind = "dangling"      // 目标不存在，通常是仓库还没有 clone
+	LinkWrongTarget LinkProblemKind = "wrong target"  // 软链指向了其他位置
+	LinkMissing     LinkProblemKind = "missing"       // 视图中应有的软链不存在
+	LinkNotSymlink  LinkProblemKind = "not a symlink" // 软链的位置被普通文件或目录占用
+	LinkUnexpected  LinkProblemKind = "unexpected"    // 视图目录中不属于任何 submodule 的条目
+)
+
+// LinkProblem 是视图中的一个问题
+type LinkProblem struct {
+	Kind   LinkProblemKind `json:"kind" yaml:"kind"`
+	Path   string          `json:"path" yaml:"path"`
+	Repo   string          `json:"repo,omitempty" yaml:"repo,omitempty"`
+	Target string          `json:"target,omitempty" yaml:"target,omitempty"` // 应当指向的目标
+	Actual string          `json:"actual,omitempty" yaml:"actual,omitempty"` // 实际指向的目标
+}
+
+// LinkCheck 是 CheckLinks 的结果
+type LinkCheck struct {
+	Root     string        `json:"-" yaml:"-"`
+	Links    int           `json:"links" yaml:"links"` // 检查的软链数
+	Problems []LinkProblem `json:"problems" yaml:"problems"`
+}
+
+// CheckLinks 检查每个视图中的软链：应有的软链是否存在、指向正确且目标存在，
+// 以及视图目录中是否有多余的条目。keep 中 submodule 的软链不在本次选择中，
+// 不会被当作多余的条目。
+func CheckLinks(cfg *config.Config, root string, keep []string) (*LinkCheck, error) {
+	_, planned, err := planLinks(cfg, root)
+	if err != nil {
+		return nil, err
+	}
+
+	check := &LinkCheck{Root: root, Links: len(planned), Problems: []LinkProblem{}}
+	expected := map[string]bool{}
+	for _, p := range planned {
+		expected[p.path] = true
+		problem := LinkProblem{Path: p.path, Repo: p.repo, Target: p.target}
+
+		info, err := os.Lstat(p.path)
+		switch {
+		case os.IsNotExist(err):
+			problem.Kind = LinkMissing
+		case err != nil:
+			return nil, err
+		case info.Mode()&os.ModeSymlink == 0:
+			problem.Kind = LinkNotSymlink
+		default:
+			if problem.Actual, _ = os.Readlink(p.path); problem.Actual != p.target {
+				problem.Kind = LinkWrongTarget
+			} else if _, err := os.Stat(p.path); err != nil {
+				problem.Kind = LinkDangling
+				problem.Actual = ""
+			}
+		}
+		if problem.Kind != "" {
+			check.Problems = append(check.Problems, problem)
+		}
+	}
+
+	kept := map[string]bool{}
+	for _, name := range keep {
+		kept[filepath.Join("..", "..", cfg.SubmodulesDir, name)] = true
+	}
+	for _, view := range cfg.LinkViews() {
+		// 视图目录的下两层分别是分组目录和软链
+		entries, err := filepath.Glob(filepath.Join(root, view.Name, "*", "*"))
+		if err != nil {
+			return nil, err
+		}
+		groups, _ := filepath.Glob(filepath.Join(root, view.Name, "*"))
+		for _, group := range groups {
+			if info, err := os.Lstat(group); err == nil && !info.IsDir() {
+				entries = append(entries, group)
+			}
+		}
+
+		for _, path := range entries {
+			if expected[path] {
+				continue
+			}
+			if dest, err := os.Readlink(path); err == nil && kept[dest] {
+				continue
+			}
+			check.Problems = append(check.Problems, LinkProblem{Kind: LinkUnexpected, Path: path})
+		}
+	}
+
+	slices.SortFunc(check.Problems, func(a, b LinkProblem) int { return strings.Compare(a.Path, b.Path) })
+	return check, nil
+}
+
+// Print 列出每个问题，没有问题时打印检查的软链数
+func (c *LinkCheck) Print() {
+	for _, p := range c.Problems {
+		rel := linkKey(c.Root, p.Path)
+		switch p.Kind {
+		case LinkWrongTarget:
+			color.Red("  [%s] %s -> %s (expected %s)", p.Kind, rel, p.Actual, p.Target)
+		case LinkMissing, LinkDangling:
+			color.Red("  [%s] %s -> %s", p.Kind, rel, p.Target)
+		default:
+			color.Red("  [%s] %s", p.Kind, rel)
+		}
+	}
+
+	if len(c.Problems) == 0 {
+		color.Green("All %d symlinks are healthy", c.Links)
+	}
+}
+
+// Err 在视图有问题时返回错误
+func (c *LinkCheck) Err() error {
+	if len(c.Problems) > 0 {
+		return fmt.Errorf("%d symlink problems found", len(c.Problems))
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package submodule
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/inspirai-store/inspirai-devkit/internal/config"
+)
+
+// linkConfig 返回按 tag 分组的单视图配置，submodule 名称到 tag 由 tags 给出
+func linkConfig(tags map[string]string) *config.Config {
+	cfg := &config.Config{
+		SubmodulesDir: ".submodules",
+		Views:         []config.View{{Name: "by-tag", GroupBy: "tag"}},
+	}
+	for _, name := range []string{"alpha", "beta", "gamma"} {
+		if tag, ok := tags[name]; ok {
+			cfg.Submodules = append(cfg.Submodules, config.SubmoduleConfig{Name: name, Tags: []string{tag}})
+		}
+	}
+	return cfg
+}
+
+// createLinks 调用 CreateLinks 并返回软链路径（相对 root）到处理方式的映射
+func createLinks(t *testing.T, cfg *config.Config, root string, opts LinkOptions) map[string]LinkAction {
+	t.Helper()
+	links, err := CreateLinks(cfg, root, opts)
+	if err != nil {
+		t.Fatalf("CreateLinks: %v", err)
+	}
+	actions := map[string]LinkAction{}
+	for _, l := range links {
+		if l.Error != "" {
+			t.Fatalf("%s: %s", l.Path, l.Error)
+		}
+		actions[linkKey(root, l.Path)] = l.Action
+	}
+	return actions
+}
+
+// readLink 返回 root 下软链的目标，不存在或不是软链时返回空字符串
+func readLink(root, rel string) string {
+	dest, _ := os.Readlink(filepath.Join(root, filepath.FromSlash(rel)))
+	return dest
+}
+
+func TestCreateLinksPrunesStaleLinks(t *testing.T) {
+	root := t.TempDir()
+	createLinks(t, linkConfig(map[string]string{"alpha": "go", "beta": "go"}), root, LinkOptions{})
+	// 用户自己创建的软链不属于 sm
+	if err := os.Symlink("elsewhere", filepath.Join(root, "by-tag", "go", "mine")); err != nil {
+		t.Fatal(err)
+	}
+
+	got := createLinks(t, linkConfig(map[string]string{"alpha": "web"}), root, LinkOptions{})
+	want := map[string]LinkAction{
+		"by-tag/web/alpha": LinkCreate,
+		"by-tag/go/alpha":  LinkRemove,
+		"by-tag/go/beta":   LinkRemove,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("links = %v, want %v", got, want)
+	}
+	for rel, action := range want {
+		if got[rel] != action {
+			t.Errorf("%s: action = %s, want %s", rel, got[rel], action)
+		}
+	}
+	if readLink(root, "by-tag/go/alpha") != "" || readLink(root, "by-tag/go/beta") != "" {
+		t.Error("stale links were not removed")
+	}
+	if readLink(root, "by-tag/go/mine") != "elsewhere" {
+		t.Error("a link not created by sm was removed")
+	}
+	if got := readLink(root, "by-tag/web/alpha"); got != filepath.Join("..", "..", ".submodules", "alpha") {
+		t.Errorf("by-tag/web/alpha -> %q", got)
+	}
+
+	state, err := config.LoadState(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Links) != 1 || state.Links["by-tag/web/alpha"] != "alpha" {
+		t.Errorf("state links = %v, want only by-tag/web/alpha", state.Links)
+	}
+}
+
+func TestCreateLinksKeep(t *testing.T) {
+	root := t.TempDir()
+	createLinks(t, linkConfig(map[string]string{"alpha": "go", "beta": "go"}), root, LinkOptions{})
+
+	// beta 不在本次选择中，它的软链保留并继续由 sm 管理
+	got := createLinks(t, linkConfig(map[string]string{"alpha": "go"}), root, LinkOptions{Keep: []string{"beta"}})
+	if _, ok := got["by-tag/go/beta"]; ok || readLink(root, "by-tag/go/beta") == "" {
+		t.Fatalf("kept link was touched: %v", got)
+	}
+
+	got = createLinks(t, linkConfig(map[string]string{"alpha": "go"}), root, LinkOptions{})
+	if got["by-tag/go/beta"] != LinkRemove || readLink(root, "by-tag/go/beta") != "" {
+		t.Errorf("link of beta was not removed once it is no longer kept: %v", got)
+	}
+}
+
+func TestCreateLinksDoesNotReplaceFiles(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "by-tag", "go"), "alpha", "mine\n")
+
+	links, err := CreateLinks(linkConfig(map[string]string{"alpha": "go", "beta": "go"}), root, LinkOptions{})
+	if err != nil {
+		t.Fatalf("CreateLinks: %v", err)
+	}
+	if LinksErr(links) == nil {
+		t.Error("LinksErr = nil, want an error for by-tag/go/alpha")
+	}
+	for _, l := range links {
+		rel := linkKey(root, l.Path)
+		if wantErr := rel == "by-tag/go/alpha"; (l.Error != "") != wantErr {
+			t.Errorf("%s: error = %q", rel, l.Error)
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "by-tag", "go", "alpha")); err != nil || string(data) != "mine\n" {
+		t.Errorf("file was replaced (content %q, err %v)", data, err)
+	}
+
+	// 文件不会因为状态中没有记录而被当作过期的软链删除
+	state, err := config.LoadState(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := state.Links["by-tag/go/alpha"]; ok {
+		t.Error("the file was recorded as an owned link")
+	}
+}
+
+func TestCreateLinksDryRun(t *testing.T) {
+	root := t.TempDir()
+	createLinks(t, linkConfig(map[string]string{"alpha": "go"}), root, LinkOptions{})
+	before, err := config.LoadState(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := createLinks(t, linkConfig(map[string]string{"beta": "web"}), root, LinkOptions{DryRun: true})
+	if got["by-tag/web/beta"] != LinkCreate || got["by-tag/go/alpha"] != LinkRemove {
+		t.Errorf("planned = %v, want create by-tag/web/beta and remove by-tag/go/alpha", got)
+	}
+	if readLink(root, "by-tag/go/alpha") == "" {
+		t.Error("dry run removed by-tag/go/alpha")
+	}
+	if _, err := os.Lstat(filepath.Join(root, "by-tag", "web")); !os.IsNotExist(err) {
+		t.Errorf("dry run created by-tag/web (err = %v)", err)
+	}
+	after, err := config.LoadState(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after.Links) != len(before.Links) || after.Links["by-tag/go/alpha"] != "alpha" {
+		t.Errorf("dry run changed the state: %v", after.Links)
+	}
+}
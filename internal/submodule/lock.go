@@ -0,0 +1,314 @@
+package submodule
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/inspirai-store/inspirai-devkit/internal/config"
+)
+
+// Lock 记录每个已 clone 的 submodule 当前的提交、分支和远程地址
+//
+// 没有 clone 的 submodule 保留 previous（已有的锁文件，可以为 nil）中的记录，
+// 使只 clone 了部分仓库的工作区不会删掉其他人锁定的提交；被禁用的 submodule
+// 同样保留原有记录。r 接收每个仓库的进度，为 nil 时不输出。
+func Lock(cfg *config.Config, root string, previous *config.Lock, r Reporter) (*config.Lock, error) {
+	r = orSilent(r)
+	submodulesDir := filepath.Join(root, cfg.SubmodulesDir)
+	lock := &config.Lock{}
+	cloned := 0
+
+	for _, sm := range cfg.Submodules {
+		r.Start(sm.Name)
+		smPath := filepath.Join(submodulesDir, sm.Name)
+		if _, err := os.Stat(smPath); os.IsNotExist(err) {
+			if previous != nil {
+				if locked, ok := previous.Find(sm.Name); ok {
+					lock.Submodules = append(lock.Submodules, locked)
+					r.Finish(RepoResult{Name: sm.Name, Outcome: OutcomeSkipped, Detail: "not cloned, keeping " + shortSHA(locked.Commit)}, nil)
+					continue
+				}
+			}
+			r.Finish(RepoResult{Name: sm.Name, Outcome: OutcomeSkipped, Detail: "not found"}, nil)
+			continue
+		}
+		cloned++
+
+		commit, err := gitOutput(smPath, "rev-parse", "HEAD")
+		if err != nil {
+			err = fmt.Errorf("%s: failed to resolve HEAD: %w", sm.Name, err)
+			r.Finish(RepoResult{Name: sm.Name, Outcome: OutcomeFailed, Detail: err.Error(), Err: err}, nil)
+			return nil, err
+		}
+		branch, _ := gitOutput(smPath, "branch", "--show-current")
+		remote, _ := gitOutput(smPath, "remote", "get-url", "origin")
+
+		lock.Submodules = append(lock.Submodules, config.LockedRepo{
+			Name:   sm.Name,
+			Commit: commit,
+			Branch: branch,
+			Remote: remote,
+		})
+		r.Progress(sm.Name, "locked "+shortSHA(commit))
+		r.Finish(RepoResult{Name: sm.Name, Outcome: OutcomeDone}, nil)
+	}
+
+	if cloned == 0 {
+		return nil, fmt.Errorf("no cloned submodules found in %s", submodulesDir)
+	}
+
+	if previous != nil {
+		for _, name := range cfg.Disabled {
+			if locked, ok := previous.Find(name); ok {
+				lock.Submodules = append(lock.Submodules, locked)
+				r.Finish(RepoResult{Name: name, Outcome: OutcomeSkipped, Detail: "disabled, keeping " + shortSHA(locked.Commit)}, nil)
+			}
+		}
+	}
+	return lock, nil
+}
+
+// CheckoutLocked 把每个 submodule 恢复到锁文件中记录的提交
+//
+// 有本地修改的仓库不会被改动；本地找不到锁定提交时会先 fetch，
+// 仍然找不到则报告为不可达。r 接收每个仓库的进度，为 nil 时不输出。
+func CheckoutLocked(cfg *config.Config, root string, lock *config.Lock, failFast bool, r Reporter) *Result {
+	r = orSilent(r)
+	submodulesDir := filepath.Join(root, cfg.SubmodulesDir)
+	result := NewResult("restored")
+
+	for i, sm := range cfg.Submodules {
+		r.Start(sm.Name)
+		locked, ok := lock.Find(sm.Name)
+		if !ok {
+			result.Skip(sm.Name, "not in "+config.LockFile)
+			r.Finish(result.Repos[len(result.Repos)-1], nil)
+			continue
+		}
+
+		smPath := filepath.Join(submodulesDir, sm.Name)
+		if _, err := os.Stat(smPath); os.IsNotExist(err) {
+			result.Skip(sm.Name, "not found")
+			r.Finish(result.Repos[len(result.Repos)-1], nil)
+			continue
+		}
+
+		r.Progress(sm.Name, "checking out "+shortSHA(locked.Commit))
+		if err := checkoutCommit(smPath, locked); err != nil {
+			result.Fail(sm.Name, err)
+			r.Finish(result.Repos[len(result.Repos)-1], nil)
+			if failFast {
+				skipRemaining(result, cfg.Submodules[i+1:])
+				break
+			}
+			continue
+		}
+
+		result.Done(sm.Name)
+		r.Finish(result.Repos[len(result.Repos)-1], nil)
+	}
+
+	return result
+}
+
+// checkoutCommit 检出锁定的提交；如果锁定的分支正好指向该提交则检出分支
+func checkoutCommit(path string, locked config.LockedRepo) error {
+	if status, err := getGitStatus(path); err != nil {
+		return err
+	} else if !status.Clean() {
+		return fmt.Errorf("working tree has %s", status)
+	}
+
+	if !hasCommit(path, locked.Commit) {
+		// 先获取所有分支，再尝试直接按 SHA 获取（服务端需允许）
+		_ = runQuiet(path, "fetch", "--tags", "origin")
+		if !hasCommit(path, locked.Commit) {
+			_ = runQuiet(path, "fetch", "origin", locked.Commit)
+		}
+		if !hasCommit(path, locked.Commit) {
+			return fmt.Errorf("locked commit %s is unreachable", locked.Commit)
+		}
+	}
+
+	if head, _ := gitOutput(path, "rev-parse", "HEAD"); head == locked.Commit {
+		return nil
+	}
+
+	if locked.Branch != "" {
+		if tip, err := gitOutput(path, "rev-parse", "--verify", "refs/heads/"+locked.Branch); err == nil && tip == locked.Commit {
+			return runQuiet(path, "checkout", locked.Branch)
+		}
+	}
+	return runQuiet(path, "-c", "advice.detachedHead=false", "checkout", "--detach", locked.Commit)
+}
+
+// DriftKind 表示工作区中的仓库与锁文件的关系
+type DriftKind string
+
+const (
+	DriftLocked   DriftKind = "locked"   // HEAD 与锁定提交一致
+	DriftAhead    DriftKind = "ahead"    // HEAD 包含锁定提交之后的新提交
+	DriftBehind   DriftKind = "behind"   // HEAD 落后于锁定提交
+	DriftDiverged DriftKind = "diverged" // 两边各有对方没有的提交
+	DriftUnknown  DriftKind = "unknown"  // 本地没有锁定的提交，无法比较
+	DriftMissing  DriftKind = "missing"  // 仓库未 clone
+	DriftUnlocked DriftKind = "unlocked" // 锁文件中没有该仓库
+	// DriftNotCloned 表示仓库既没有 clone 也没有锁定，部分 clone 的工作区中视为一致
+	DriftNotCloned DriftKind = "not cloned"
+	// DriftUnmanaged 表示锁文件中的仓库已经不在 manifest 中
+	DriftUnmanaged DriftKind = "not in manifest"
+)
+
+// Drift 描述单个仓库相对锁文件的偏移
+type Drift struct {
+	Kind   DriftKind `json:"kind" yaml:"kind"`
+	Ahead  int       `json:"ahead,omitempty" yaml:"ahead,omitempty"`
+	Behind int       `json:"behind,omitempty" yaml:"behind,omitempty"`
+}
+
+func (d Drift) String() string {
+	switch d.Kind {
+	case DriftAhead:
+		return fmt.Sprintf("ahead %d", d.Ahead)
+	case DriftBehind:
+		return fmt.Sprintf("behind %d", d.Behind)
+	case DriftDiverged:
+		return fmt.Sprintf("diverged +%d/-%d", d.Ahead, d.Behind)
+	}
+	return string(d.Kind)
+}
+
+// Consistent 判断仓库是否与锁文件一致
+func (d Drift) Consistent() bool {
+	return d.Kind == DriftLocked || d.Kind == DriftNotCloned
+}
+
+// lockDrift 比较仓库 HEAD 与锁定的提交
+func lockDrift(path string, locked config.LockedRepo) Drift {
+	head, err := gitOutput(path, "rev-parse", "HEAD")
+	if err != nil {
+		return Drift{Kind: DriftUnknown}
+	}
+	if head == locked.Commit {
+		return Drift{Kind: DriftLocked}
+	}
+	if !hasCommit(path, locked.Commit) {
+		return Drift{Kind: DriftUnknown}
+	}
+
+	out, err := gitOutput(path, "rev-list", "--left-right", "--count", "HEAD..."+locked.Commit)
+	if err != nil {
+		return Drift{Kind: DriftUnknown}
+	}
+	var d Drift
+	if _, err := fmt.Sscanf(out, "%d %d", &d.Ahead, &d.Behind); err != nil {
+		return Drift{Kind: DriftUnknown}
+	}
+
+	switch {
+	case d.Ahead > 0 && d.Behind > 0:
+		d.Kind = DriftDiverged
+	case d.Ahead > 0:
+		d.Kind = DriftAhead
+	case d.Behind > 0:
+		d.Kind = DriftBehind
+	default:
+		// 锁定的是缩写 SHA 或 tag 时可能指向同一个提交
+		d.Kind = DriftLocked
+	}
+	return d
+}
+
+// submoduleDrift 返回单个 submodule 相对锁文件的偏移
+func submoduleDrift(submodulesDir string, sm config.SubmoduleConfig, lock *config.Lock) Drift {
+	locked, ok := lock.Find(sm.Name)
+	smPath := filepath.Join(submodulesDir, sm.Name)
+	_, err := os.Stat(smPath)
+	missing := os.IsNotExist(err)
+
+	switch {
+	case !ok && missing:
+		return Drift{Kind: DriftNotCloned}
+	case !ok:
+		return Drift{Kind: DriftUnlocked}
+	case missing:
+		return Drift{Kind: DriftMissing}
+	}
+	return lockDrift(smPath, locked)
+}
+
+// LockStatus 是单个仓库与锁文件的比较结果
+type LockStatus struct {
+	Name   string `json:"name" yaml:"name"`
+	Drift  Drift  `json:"drift" yaml:"drift"`
+	Commit string `json:"commit,omitempty" yaml:"commit,omitempty"` // 锁定的提交
+}
+
+// LockReport 是 CheckLock 的结果
+type LockReport []LockStatus
+
+// CheckLock 比较工作区中的每个仓库与锁文件，包括锁文件中有但 manifest 中已经没有的仓库；
+// skip 中的 submodule（不在当前选择中）和被禁用的 submodule 不做比较
+func CheckLock(cfg *config.Config, root string, lock *config.Lock, skip []string) LockReport {
+	submodulesDir := filepath.Join(root, cfg.SubmodulesDir)
+
+	report := LockReport{}
+	for _, sm := range cfg.Submodules {
+		if slices.Contains(skip, sm.Name) {
+			continue
+		}
+		locked, _ := lock.Find(sm.Name)
+		report = append(report, LockStatus{Name: sm.Name, Drift: submoduleDrift(submodulesDir, sm, lock), Commit: locked.Commit})
+	}
+	for _, locked := range lock.Submodules {
+		// 被禁用的 submodule 与未选择的一样不参与比较
+		if slices.Contains(cfg.Disabled, locked.Name) {
+			continue
+		}
+		if !slices.ContainsFunc(cfg.Submodules, func(sm config.SubmoduleConfig) bool { return sm.Name == locked.Name }) {
+			report = append(report, LockStatus{Name: locked.Name, Drift: Drift{Kind: DriftUnmanaged}, Commit: locked.Commit})
+		}
+	}
+	return report
+}
+
+// Print 以表格打印比较结果
+func (r LockReport) Print() {
+	fmt.Printf("%-20s %-18s %s\n", "NAME", "LOCK", "COMMIT")
+	fmt.Println(strings.Repeat("-", 70))
+
+	for _, s := range r {
+		fmt.Printf("%-20s ", s.Name)
+		if s.Drift.Consistent() {
+			color.Green("%-18s %s", s.Drift, shortSHA(s.Commit))
+		} else {
+			color.Red("%-18s %s", s.Drift, shortSHA(s.Commit))
+		}
+	}
+
+	if r.Err() == nil {
+		color.Green("\nAll submodules match %s", config.LockFile)
+	}
+}
+
+// Err 在有仓库与锁文件不一致时返回错误
+func (r LockReport) Err() error {
+	var drifted []string
+	for _, s := range r {
+		if !s.Drift.Consistent() {
+			drifted = append(drifted, s.Name)
+		}
+	}
+	if len(drifted) > 0 {
+		return fmt.Errorf("%d submodules drifted from %s: %s", len(drifted), config.LockFile, strings.Join(drifted, ", "))
+	}
+	return nil
+}
+
+func shortSHA(sha string) string {
+	return sha[:min(12, len(sha))]
+}
@@ -0,0 +1,110 @@
+package submodule
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/inspirai-store/inspirai-devkit/internal/config"
+)
+
+func TestLockKeepsEntriesOfMissingRepos(t *testing.T) {
+	tests := []struct {
+		name string
+		// edit 在第二次 Lock 之前修改工作区和配置
+		edit func(t *testing.T, root string, cfg *config.Config)
+		// skip 是 sm lock --check 时不在选择中的仓库
+		skip []string
+	}{
+		{
+			name: "not cloned",
+			edit: func(t *testing.T, root string, cfg *config.Config) {
+				if err := os.RemoveAll(filepath.Join(root, ".submodules", "beta")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			skip: []string{"beta"},
+		},
+		{
+			name: "disabled",
+			edit: func(t *testing.T, root string, cfg *config.Config) {
+				cfg.Submodules = cfg.Submodules[:1]
+				cfg.Disabled = []string{"beta"}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolateGit(t)
+			bare, _ := newOrigin(t)
+			root := t.TempDir()
+			cfg := &config.Config{SubmodulesDir: ".submodules"}
+			for _, name := range []string{"alpha", "beta"} {
+				git(t, root, "clone", "-q", bare, filepath.Join(root, ".submodules", name))
+				cfg.Submodules = append(cfg.Submodules, config.SubmoduleConfig{Name: name, Repo: bare})
+			}
+
+			previous, err := Lock(cfg, root, nil, nil)
+			if err != nil {
+				t.Fatalf("Lock: %v", err)
+			}
+			tt.edit(t, root, cfg)
+
+			lock, err := Lock(cfg, root, previous, nil)
+			if err != nil {
+				t.Fatalf("Lock: %v", err)
+			}
+			if got, ok := lock.Find("beta"); !ok || got != previous.Submodules[1] {
+				t.Errorf("beta = %+v (found %v), want %+v", got, ok, previous.Submodules[1])
+			}
+
+			report := CheckLock(cfg, root, lock, tt.skip)
+			if err := report.Err(); err != nil {
+				t.Errorf("CheckLock: %v (%+v)", err, report)
+			}
+		})
+	}
+}
+
+func TestCheckLockDrift(t *testing.T) {
+	isolateGit(t)
+	bare, _ := newOrigin(t)
+	root := t.TempDir()
+	smPath := filepath.Join(root, ".submodules", "alpha")
+	git(t, root, "clone", "-q", bare, smPath)
+	cfg := &config.Config{
+		SubmodulesDir: ".submodules",
+		Submodules:    []config.SubmoduleConfig{{Name: "alpha", Repo: bare}, {Name: "beta", Repo: bare}},
+	}
+
+	lock, err := Lock(cfg, root, nil, nil)
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	lock.Submodules = append(lock.Submodules, config.LockedRepo{Name: "gone", Commit: lock.Submodules[0].Commit})
+	commitFile(t, smPath, "README", "local\n")
+
+	want := map[string]DriftKind{
+		"alpha": DriftAhead,
+		"beta":  DriftNotCloned,
+		"gone":  DriftUnmanaged,
+	}
+	report := CheckLock(cfg, root, lock, nil)
+	if len(report) != len(want) {
+		t.Fatalf("report = %+v, want %d entries", report, len(want))
+	}
+	for _, s := range report {
+		if s.Drift.Kind != want[s.Name] {
+			t.Errorf("%s: drift = %s, want %s", s.Name, s.Drift.Kind, want[s.Name])
+		}
+	}
+	if report.Err() == nil {
+		t.Error("Err() = nil, want drift error")
+	}
+
+	// 不在选择中的仓库不做比较
+	if got := CheckLock(cfg, root, lock, []string{"alpha"}); len(got) != 2 {
+		t.Errorf("report with alpha skipped = %+v", got)
+	}
+}
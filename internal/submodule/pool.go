@@ -0,0 +1,47 @@
+package submodule
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/inspirai-store/inspirai-devkit/internal/config"
+)
+
+// runPool 用 jobs 个 worker 并发处理每个 submodule，结果按配置顺序写入 Result
+//
+// failFast 为 true 时一旦出现失败就不再开始新的任务，尚未开始的 submodule
+// 记录为未执行。
+func runPool(submodules []config.SubmoduleConfig, jobs int, failFast bool, verb string, work func(sm config.SubmoduleConfig) RepoResult) *Result {
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	result := &Result{Verb: verb, Repos: make([]RepoResult, len(submodules))}
+	var failed atomic.Bool
+
+	// 先占用一个空闲的 worker 再检查 failed，保证之前的任务结束后才决定是否开始下一个
+	slots := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i, sm := range submodules {
+		slots <- struct{}{}
+		if failFast && failed.Load() {
+			result.Repos[i] = RepoResult{Name: sm.Name, Outcome: OutcomeSkipped, Detail: "not attempted (fail-fast)"}
+			<-slots
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			repo := work(sm)
+			result.Repos[i] = repo
+			if repo.Outcome == OutcomeFailed || repo.Outcome == OutcomeConflicted {
+				failed.Store(true)
+			}
+			<-slots
+		}()
+	}
+	wg.Wait()
+
+	return result
+}
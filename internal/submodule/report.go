@@ -0,0 +1,100 @@
+package submodule
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// reportLogLimit 是同步报告中每个仓库最多列出的新提交数
+const reportLogLimit = 10
+
+// SyncChange 记录一次同步中单个仓库拉取到的变更
+type SyncChange struct {
+	Name    string  `json:"name" yaml:"name"`
+	Outcome Outcome `json:"outcome" yaml:"outcome"`
+	Detail  string  `json:"detail,omitempty" yaml:"detail,omitempty"`
+	Before  string  `json:"before,omitempty" yaml:"before,omitempty"` // 同步前的 HEAD
+	After   string  `json:"after,omitempty" yaml:"after,omitempty"`   // 同步后的 HEAD
+	Commits int     `json:"commits" yaml:"commits"`                   // 新提交数，不含 rebase 后重写的本地提交
+	// Log 是新提交的单行日志，最多 reportLogLimit 条
+	Log   []string `json:"log,omitempty" yaml:"log,omitempty"`
+	Files []string `json:"files,omitempty" yaml:"files,omitempty"` // 变更的文件
+}
+
+// SyncReport 是 Sync 的结果
+type SyncReport struct {
+	Repos  []SyncChange
+	result *Result
+}
+
+// Print 列出有新提交的仓库并打印汇总表
+func (r *SyncReport) Print() {
+	printSyncReport(r.Repos)
+	r.result.PrintSummary()
+}
+
+// Err 在有仓库同步失败时返回 *Error
+func (r *SyncReport) Err() error {
+	return r.result.Err()
+}
+
+// recordChange 比较同步前后的 HEAD，填充新提交和变更文件
+func recordChange(path string, change *SyncChange) {
+	change.After, _ = gitOutput(path, "rev-parse", "HEAD")
+	if change.Before == "" || change.After == "" || change.Before == change.After {
+		return
+	}
+
+	// --cherry-pick 排除 rebase 后内容相同的本地提交，只留下新拉取的提交
+	incoming := []string{"--cherry-pick", "--right-only", change.Before + "..." + change.After}
+	if out, err := gitOutput(path, append([]string{"rev-list", "--count"}, incoming...)...); err == nil {
+		fmt.Sscan(out, &change.Commits)
+	}
+	if out, err := gitOutput(path, append([]string{"log", "--oneline", "--no-decorate", fmt.Sprintf("-%d", reportLogLimit)}, incoming...)...); err == nil && out != "" {
+		change.Log = strings.Split(out, "\n")
+	}
+	if out, err := gitOutput(path, "diff", "--name-only", change.Before, change.After); err == nil && out != "" {
+		change.Files = strings.Split(out, "\n")
+	}
+}
+
+// newSyncReport 按汇总结果的顺序合并每个仓库的变更
+func newSyncReport(result *Result, changes map[string]SyncChange) *SyncReport {
+	report := &SyncReport{Repos: make([]SyncChange, 0, len(result.Repos)), result: result}
+	for _, repo := range result.Repos {
+		change := changes[repo.Name]
+		change.Name = repo.Name
+		change.Outcome = repo.Outcome
+		change.Detail = repo.Detail
+		report.Repos = append(report.Repos, change)
+	}
+	return report
+}
+
+// printSyncReport 列出有新提交的仓库
+func printSyncReport(report []SyncChange) {
+	var changed []SyncChange
+	for _, change := range report {
+		if change.Commits > 0 || len(change.Files) > 0 {
+			changed = append(changed, change)
+		}
+	}
+	if len(changed) == 0 {
+		fmt.Println("\nNo new commits.")
+		return
+	}
+
+	color.Cyan("\nChanges:")
+	for _, change := range changed {
+		fmt.Printf("\n%s %s..%s: %d new commits, %d files changed\n",
+			color.New(color.Bold).Sprint(change.Name), shortSHA(change.Before), shortSHA(change.After), change.Commits, len(change.Files))
+		for _, line := range change.Log {
+			fmt.Printf("  %s\n", line)
+		}
+		if more := change.Commits - len(change.Log); more > 0 {
+			fmt.Printf("  ... and %d more\n", more)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package submodule
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Reporter 接收批量操作（Init、Sync、Status、CreateLinks、Run、Lock、Retire 等）
+// 中每个仓库的事件，决定如何展示。并发执行时方法会被多个 goroutine 同时调用。
+type Reporter interface {
+	// Start 在开始处理一个仓库时调用
+	Start(repo string)
+	// Progress 报告仓库的进展，如 cloning、fetching、rebasing 或创建的软链
+	Progress(repo, message string)
+	// Finish 在仓库处理结束时调用，output 是该仓库的 git 输出
+	Finish(result RepoResult, output []byte)
+	// Error 报告不影响其他仓库的错误，如某个软链创建失败
+	Error(repo string, err error)
+}
+
+// orSilent 在没有指定 Reporter 时丢弃所有事件
+func orSilent(r Reporter) Reporter {
+	if r == nil {
+		return Silent
+	}
+	return r
+}
+
+// Silent 丢弃所有事件
+var Silent Reporter = silentReporter{}
+
+type silentReporter struct{}
+
+func (silentReporter) Start(string)              {}
+func (silentReporter) Progress(string, string)   {}
+func (silentReporter) Finish(RepoResult, []byte) {}
+func (silentReporter) Error(string, error)       {}
+
+// TerminalReporter 在终端上展示事件
+//
+// 默认每个事件输出一行；live 为 true 时每个仓库占一行，状态变化时用 ANSI
+// 控制序列原地重绘，失败仓库的 git 输出在 Close 时统一打印。
+type TerminalReporter struct {
+	mu   sync.Mutex
+	out  io.Writer
+	live bool
+
+	// live 模式的状态
+	names    []string
+	phases   map[string]string
+	finished map[string]bool
+	failed   []failedOutput
+	drawn    int
+}
+
+type failedOutput struct {
+	name   string
+	output []byte
+}
+
+// NewTerminalReporter 创建输出到 out 的终端 Reporter
+func NewTerminalReporter(out io.Writer, live bool) *TerminalReporter {
+	return &TerminalReporter{out: out, live: live, phases: map[string]string{}, finished: map[string]bool{}}
+}
+
+func (r *TerminalReporter) Start(repo string) {
+	if !r.live {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.update(repo, "started")
+}
+
+func (r *TerminalReporter) Progress(repo, message string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.live {
+		fmt.Fprintln(r.out, color.CyanString("  [%s] %s", repo, message))
+		return
+	}
+	// 仓库结束后的进展（如 Init 最后创建的软链）不再改变它的状态行
+	if !r.finished[repo] {
+		r.update(repo, message)
+	}
+}
+
+func (r *TerminalReporter) Finish(result RepoResult, output []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	failed := result.Outcome == OutcomeFailed || result.Outcome == OutcomeConflicted
+	if r.live {
+		r.finished[result.Name] = true
+		r.update(result.Name, string(result.Outcome))
+		if failed && len(output) > 0 {
+			r.failed = append(r.failed, failedOutput{result.Name, output})
+		}
+		return
+	}
+
+	switch result.Outcome {
+	case OutcomeSkipped:
+		fmt.Fprintln(r.out, color.YellowString("  [skip] %s %s", result.Name, result.Detail))
+	case OutcomeConflicted:
+		fmt.Fprintln(r.out, color.RedString("  [conflict] %s: %s", result.Name, result.Detail))
+	case OutcomeFailed:
+		fmt.Fprintln(r.out, color.RedString("  [error] %s: %s", result.Name, result.Detail))
+	default:
+		fmt.Fprintln(r.out, color.GreenString("  [done] %s", result.Name))
+	}
+	if failed {
+		for _, line := range strings.Split(strings.TrimRight(string(output), "\n"), "\n") {
+			if line != "" {
+				fmt.Fprintf(r.out, "      %s\n", line)
+			}
+		}
+	}
+}
+
+func (r *TerminalReporter) Error(repo string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.live && !r.finished[repo] {
+		r.update(repo, "error")
+	}
+	// live 模式下也直接输出错误，之后的重绘从错误下方开始
+	fmt.Fprintln(r.out, color.RedString("  [error] %s: %v", repo, err))
+	r.drawn = 0
+}
+
+// Close 结束 live 显示并打印失败仓库的 git 输出
+func (r *TerminalReporter) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, f := range r.failed {
+		fmt.Fprintln(r.out, color.RedString("\n=== %s ===", f.name))
+		r.out.Write(f.output)
+	}
+	r.failed = nil
+	return nil
+}
+
+// update 更新仓库在 live 视图中的状态并重绘
+func (r *TerminalReporter) update(repo, phase string) {
+	if _, ok := r.phases[repo]; !ok {
+		r.names = append(r.names, repo)
+	}
+	r.phases[repo] = phase
+
+	width := 0
+	for _, name := range r.names {
+		width = max(width, len(name))
+	}
+
+	var b strings.Builder
+	if r.drawn > 0 {
+		// 回到第一行重新绘制
+		fmt.Fprintf(&b, "\x1b[%dA", r.drawn)
+	}
+	for _, name := range r.names {
+		phase := r.phases[name]
+		fmt.Fprintf(&b, "\x1b[2K  %-*s  %s\n", width, name, phaseColor(phase).Sprint(phase))
+	}
+	io.WriteString(r.out, b.String())
+	r.drawn = len(r.names)
+}
+
+func phaseColor(phase string) *color.Color {
+	switch Outcome(phase) {
+	case OutcomeDone:
+		return color.New(color.FgGreen)
+	case OutcomeFailed, OutcomeConflicted, "error":
+		return color.New(color.FgRed)
+	case OutcomeSkipped:
+		return color.New(color.FgYellow)
+	}
+	return color.New(color.FgCyan)
+}
+
+// JSONReporter 把每个事件输出为一行 JSON（JSON Lines）
+type JSONReporter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONReporter 创建输出到 w 的 JSON Lines Reporter
+func NewJSONReporter(w io.Writer) *JSONReporter {
+	return &JSONReporter{enc: json.NewEncoder(w)}
+}
+
+// jsonEvent 是 JSONReporter 输出的一行
+type jsonEvent struct {
+	Time    time.Time `json:"time"`
+	Event   string    `json:"event"` // started, progress, finished, error
+	Repo    string    `json:"repo"`
+	Message string    `json:"message,omitempty"`
+	Outcome Outcome   `json:"outcome,omitempty"`
+	Detail  string    `json:"detail,omitempty"`
+	Output  string    `json:"output,omitempty"`
+}
+
+func (r *JSONReporter) emit(e jsonEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e.Time = time.Now()
+	r.enc.Encode(e)
+}
+
+func (r *JSONReporter) Start(repo string) {
+	r.emit(jsonEvent{Event: "started", Repo: repo})
+}
+
+func (r *JSONReporter) Progress(repo, message string) {
+	r.emit(jsonEvent{Event: "progress", Repo: repo, Message: message})
+}
+
+func (r *JSONReporter) Finish(result RepoResult, output []byte) {
+	r.emit(jsonEvent{Event: "finished", Repo: result.Name, Outcome: result.Outcome, Detail: result.Detail, Output: string(output)})
+}
+
+func (r *JSONReporter) Error(repo string, err error) {
+	r.emit(jsonEvent{Event: "error", Repo: repo, Message: err.Error()})
+}
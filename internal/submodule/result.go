@@ -0,0 +1,139 @@
+package submodule
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/inspirai-store/inspirai-devkit/internal/config"
+)
+
+// Outcome 表示单个 submodule 的处理结果
+type Outcome string
+
+const (
+	OutcomeDone    Outcome = "done"
+	OutcomeSkipped Outcome = "skipped"
+	OutcomeFailed  Outcome = "failed"
+	// OutcomeConflicted 表示同步时发生冲突，操作已被中止，仓库恢复到同步前的状态
+	OutcomeConflicted Outcome = "conflicted"
+)
+
+// RepoResult 记录一次批量操作中单个 submodule 的结果
+type RepoResult struct {
+	Name    string  `json:"name" yaml:"name"`
+	Outcome Outcome `json:"outcome" yaml:"outcome"`
+	Detail  string  `json:"detail,omitempty" yaml:"detail,omitempty"` // 跳过或失败的原因
+	Err     error   `json:"-" yaml:"-"`
+}
+
+// Result 汇总一次批量操作（init、sync、run）中每个 submodule 的结果
+type Result struct {
+	// Verb 是成功时在汇总表中显示的动词，如 cloned、synced
+	Verb  string       `json:"verb" yaml:"verb"`
+	Repos []RepoResult `json:"repos" yaml:"repos"`
+}
+
+// NewResult 创建一个空的汇总结果
+func NewResult(verb string) *Result {
+	return &Result{Verb: verb}
+}
+
+// Done 记录一个成功的 submodule
+func (r *Result) Done(name string) {
+	r.Repos = append(r.Repos, RepoResult{Name: name, Outcome: OutcomeDone})
+}
+
+// Skip 记录一个被跳过的 submodule
+func (r *Result) Skip(name, reason string) {
+	r.Repos = append(r.Repos, RepoResult{Name: name, Outcome: OutcomeSkipped, Detail: reason})
+}
+
+// Fail 记录一个失败的 submodule
+func (r *Result) Fail(name string, err error) {
+	r.Repos = append(r.Repos, RepoResult{Name: name, Outcome: OutcomeFailed, Detail: err.Error(), Err: err})
+}
+
+// skipRemaining 在 fail-fast 中止后把剩余的 submodule 记录为未执行
+func skipRemaining(result *Result, rest []config.SubmoduleConfig) {
+	for _, sm := range rest {
+		result.Skip(sm.Name, "not attempted (fail-fast)")
+	}
+}
+
+// Failed 返回所有失败（包括冲突）的 submodule
+func (r *Result) Failed() []RepoResult {
+	var failed []RepoResult
+	for _, repo := range r.Repos {
+		if repo.Outcome == OutcomeFailed || repo.Outcome == OutcomeConflicted {
+			failed = append(failed, repo)
+		}
+	}
+	return failed
+}
+
+// Err 在有 submodule 失败时返回 *Error，否则返回 nil
+func (r *Result) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	return &Error{Verb: r.Verb, Failed: failed, Total: len(r.Repos)}
+}
+
+// PrintSummary 打印汇总表
+func (r *Result) PrintSummary() {
+	counts := map[Outcome]int{}
+
+	fmt.Printf("\n%-20s %-10s %s\n", "NAME", "RESULT", "DETAIL")
+	fmt.Println(strings.Repeat("-", 50))
+	for _, repo := range r.Repos {
+		counts[repo.Outcome]++
+
+		label := string(repo.Outcome)
+		c := color.New(color.FgGreen)
+		switch repo.Outcome {
+		case OutcomeDone:
+			label = r.Verb
+		case OutcomeSkipped:
+			c = color.New(color.FgYellow)
+		case OutcomeFailed, OutcomeConflicted:
+			c = color.New(color.FgRed)
+		}
+
+		fmt.Printf("%-20s ", repo.Name)
+		c.Printf("%-10s ", label)
+		fmt.Printf("%s\n", repo.Detail)
+	}
+	fmt.Printf("\n%d %s, %d skipped, %d failed", counts[OutcomeDone], r.Verb, counts[OutcomeSkipped], counts[OutcomeFailed])
+	if counts[OutcomeConflicted] > 0 {
+		fmt.Printf(", %d conflicted", counts[OutcomeConflicted])
+	}
+	fmt.Println()
+}
+
+// Error 是批量操作中部分 submodule 失败时返回的聚合错误
+type Error struct {
+	Verb   string
+	Failed []RepoResult
+	Total  int
+}
+
+func (e *Error) Error() string {
+	names := make([]string, len(e.Failed))
+	for i, repo := range e.Failed {
+		names[i] = repo.Name
+	}
+	return fmt.Sprintf("%d of %d submodules failed: %s", len(e.Failed), e.Total, strings.Join(names, ", "))
+}
+
+// Unwrap 返回每个失败 submodule 的原始错误，便于 errors.Is/As 检查
+func (e *Error) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed))
+	for _, repo := range e.Failed {
+		if repo.Err != nil {
+			errs = append(errs, repo.Err)
+		}
+	}
+	return errs
+}
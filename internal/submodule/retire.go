@@ -0,0 +1,176 @@
+package submodule
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/inspirai-store/inspirai-devkit/internal/config"
+)
+
+// RetireMode 决定不再需要的 submodule 如何处理
+type RetireMode string
+
+const (
+	RetireKeep    RetireMode = "keep"
+	RetireArchive RetireMode = "archive"
+	RetireRemove  RetireMode = "remove"
+)
+
+// retireVerbs 是每种处理方式在汇总表中显示的动词
+var retireVerbs = map[RetireMode]string{
+	RetireKeep:    "kept",
+	RetireArchive: "archived",
+	RetireRemove:  "removed",
+}
+
+// ArchiveDir 是归档目录（相对 submodules 目录）
+const ArchiveDir = ".archive"
+
+// Retire 处理不再属于当前 profile 的 submodule
+//
+// archive 会把仓库移动到 <submodules_dir>/.archive/ 下；remove 会在确认仓库
+// 干净且已全部推送后删除。两种方式都会删除指向该仓库的软链。keep 时仓库
+// 全部记录为跳过。r 接收每个仓库的进度，为 nil 时不输出。
+func Retire(cfg *config.Config, root string, names []string, mode RetireMode, r Reporter) (*Result, error) {
+	r = orSilent(r)
+	submodulesDir := filepath.Join(root, cfg.SubmodulesDir)
+	result := NewResult(retireVerbs[mode])
+
+	if mode == RetireKeep {
+		for _, name := range names {
+			result.Skip(name, "kept (use --archive or --remove)")
+		}
+		return result, nil
+	}
+
+	for _, name := range names {
+		r.Start(name)
+		smPath := filepath.Join(submodulesDir, name)
+
+		var err error
+		switch mode {
+		case RetireArchive:
+			err = archiveSubmodule(submodulesDir, name)
+		case RetireRemove:
+			if err = checkDisposable(smPath); err == nil {
+				err = os.RemoveAll(smPath)
+			}
+		default:
+			return nil, fmt.Errorf("unknown retire mode %q", mode)
+		}
+
+		if err == nil {
+			err = RemoveLinks(cfg, root, name, r)
+		}
+		if err != nil {
+			result.Fail(name, err)
+		} else {
+			result.Done(name)
+		}
+		r.Finish(result.Repos[len(result.Repos)-1], nil)
+	}
+
+	return result, nil
+}
+
+// archiveSubmodule 把仓库移动到归档目录，已存在同名归档时追加时间戳
+func archiveSubmodule(submodulesDir, name string) error {
+	archiveDir := filepath.Join(submodulesDir, ArchiveDir)
+	if err := os.MkdirAll(archiveDir, 0755); err != nil {
+		return err
+	}
+
+	dest := filepath.Join(archiveDir, name)
+	if _, err := os.Lstat(dest); err == nil {
+		dest = fmt.Sprintf("%s-%s", dest, time.Now().Format("20060102-150405"))
+	}
+	return os.Rename(filepath.Join(submodulesDir, name), dest)
+}
+
+// checkDisposable 确认仓库没有未提交的修改、未推送的提交（包括 detached HEAD 上的提交）
+// 和 stash，可以安全删除
+func checkDisposable(path string) error {
+	if status, err := getGitStatus(path); err != nil {
+		return err
+	} else if !status.Clean() {
+		return fmt.Errorf("working tree has %s", status)
+	}
+
+	out, err := gitOutput(path, "rev-list", "HEAD", "--branches", "--not", "--remotes")
+	if err != nil {
+		return fmt.Errorf("failed to check unpushed commits: %w", err)
+	}
+	if out != "" {
+		return fmt.Errorf("%d unpushed commits", len(strings.Split(out, "\n")))
+	}
+
+	out, err = gitOutput(path, "stash", "list")
+	if err != nil {
+		return fmt.Errorf("failed to check stashes: %w", err)
+	}
+	if out != "" {
+		return fmt.Errorf("%d stash entries", len(strings.Split(out, "\n")))
+	}
+	return nil
+}
+
+// RemoveLinks 删除各个视图中指向指定 submodule 的软链，并从本地状态中移除它们；
+// 删除的每个软链作为 name 的进度报告给 r（可以为 nil）
+func RemoveLinks(cfg *config.Config, root, name string, r Reporter) error {
+	r = orSilent(r)
+	target := filepath.Join(root, cfg.SubmodulesDir, name)
+	state, err := config.LoadState(root)
+	if err != nil {
+		return err
+	}
+	removed := 0
+
+	for _, view := range cfg.LinkViews() {
+		viewDir := filepath.Join(root, view.Name)
+		groups, err := os.ReadDir(viewDir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, group := range groups {
+			if !group.IsDir() {
+				continue
+			}
+			groupDir := filepath.Join(viewDir, group.Name())
+			entries, err := os.ReadDir(groupDir)
+			if err != nil {
+				return err
+			}
+			for _, entry := range entries {
+				linkPath := filepath.Join(groupDir, entry.Name())
+				dest, err := os.Readlink(linkPath)
+				if err != nil {
+					continue // 不是软链
+				}
+				if !filepath.IsAbs(dest) {
+					dest = filepath.Join(groupDir, dest)
+				}
+				if filepath.Clean(dest) == target {
+					if err := os.Remove(linkPath); err != nil {
+						return err
+					}
+					key := linkKey(root, linkPath)
+					r.Progress(name, "unlink "+key)
+					delete(state.Links, key)
+					removed++
+				}
+			}
+		}
+	}
+
+	if removed == 0 {
+		return nil
+	}
+	return config.SaveState(root, state)
+}
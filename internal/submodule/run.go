@@ -5,8 +5,8 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
-	"github.com/fatih/color"
 	"github.com/inspirai-store/inspirai-devkit/internal/config"
 )
 
@@ -20,8 +20,17 @@ const (
 	RunnerUnknown RunnerType = "unknown"
 )
 
-// Run 在指定项目中执行命令
-func Run(cfg *config.Config, root string, projectName string, command string) error {
+// RunOptions 控制 RunAll 的行为
+type RunOptions struct {
+	// FailFast 为 true 时某个项目失败后立即停止
+	FailFast bool
+	// Reporter 接收每个项目的开始、执行的命令和结果，为 nil 时不输出
+	Reporter Reporter
+}
+
+// Run 在指定项目中执行命令，命令的输入输出直接连接到当前终端
+func Run(cfg *config.Config, root string, projectName string, command string, r Reporter) error {
+	r = orSilent(r)
 	submodulesDir := filepath.Join(root, cfg.SubmodulesDir)
 	projectPath := filepath.Join(submodulesDir, projectName)
 
@@ -37,19 +46,18 @@ func Run(cfg *config.Config, root string, projectName string, command string) er
 	}
 
 	// 构建命令
-	var cmd *exec.Cmd
+	var args []string
 	switch runner {
 	case RunnerJust:
-		color.Cyan("  [just] %s %s", projectName, command)
-		cmd = exec.Command("just", command)
+		args = []string{"just", command}
 	case RunnerNpm:
-		color.Cyan("  [npm] %s run %s", projectName, command)
-		cmd = exec.Command("npm", "run", command)
+		args = []string{"npm", "run", command}
 	case RunnerMake:
-		color.Cyan("  [make] %s %s", projectName, command)
-		cmd = exec.Command("make", command)
+		args = []string{"make", command}
 	}
+	r.Progress(projectName, strings.Join(args, " "))
 
+	cmd := exec.Command(args[0], args[1:]...)
 	cmd.Dir = projectPath
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -58,41 +66,67 @@ func Run(cfg *config.Config, root string, projectName string, command string) er
 	return cmd.Run()
 }
 
-// RunProduct 运行指定产品线的所有项目
-func RunProduct(cfg *config.Config, root string, product string, command string) error {
-	found := false
-	for _, sm := range cfg.Submodules {
-		if sm.Product == product {
-			found = true
-			color.Cyan("\n=== %s ===", sm.Name)
-			if err := Run(cfg, root, sm.Name, command); err != nil {
-				color.Red("  [error] %s: %v", sm.Name, err)
-				// 继续执行其他项目，不中断
+// RunAll 在 cfg 的每个项目中依次执行命令，返回每个项目的结果
+//
+// 默认某个项目失败后继续执行其他项目，FailFast 为 true 时立即停止。
+func RunAll(cfg *config.Config, root string, command string, opts RunOptions) (*Result, error) {
+	r := orSilent(opts.Reporter)
+
+	projects := cfg.Submodules
+	if len(projects) == 0 {
+		return nil, fmt.Errorf("no projects to run %q in", command)
+	}
+
+	result := NewResult("ran")
+	for i, sm := range projects {
+		r.Start(sm.Name)
+		if err := Run(cfg, root, sm.Name, command, r); err != nil {
+			result.Fail(sm.Name, err)
+			r.Finish(result.Repos[len(result.Repos)-1], nil)
+			if opts.FailFast {
+				skipRemaining(result, projects[i+1:])
+				break
 			}
+			continue
 		}
+		result.Done(sm.Name)
+		r.Finish(result.Repos[len(result.Repos)-1], nil)
 	}
 
-	if !found {
-		return fmt.Errorf("no projects found for product '%s'", product)
-	}
-	return nil
+	return result, nil
 }
 
-// ListRunnable 列出所有可运行的项目及其运行器类型
-func ListRunnable(cfg *config.Config, root string) {
-	submodulesDir := filepath.Join(root, cfg.SubmodulesDir)
+// Runnable 是一个项目及其检测到的运行器
+type Runnable struct {
+	Project string     `json:"project" yaml:"project"`
+	Product string     `json:"product" yaml:"product"`
+	Tags    []string   `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Runner  RunnerType `json:"runner" yaml:"runner"`
+}
 
-	fmt.Printf("%-20s %-10s %-10s\n", "PROJECT", "PRODUCT", "RUNNER")
-	fmt.Println("----------------------------------------")
+// ListRunnable 列出所有项目及其运行器类型
+func ListRunnable(cfg *config.Config, root string) []Runnable {
+	submodulesDir := filepath.Join(root, cfg.SubmodulesDir)
 
+	runnables := []Runnable{}
 	for _, sm := range cfg.Submodules {
 		projectPath := filepath.Join(submodulesDir, sm.Name)
-		runner := detectRunner(projectPath)
-		runnerStr := string(runner)
-		if runner == RunnerUnknown {
+		runnables = append(runnables, Runnable{Project: sm.Name, Product: sm.Product, Tags: sm.Tags, Runner: detectRunner(projectPath)})
+	}
+	return runnables
+}
+
+// PrintRunnable 以表格打印 ListRunnable 的结果
+func PrintRunnable(runnables []Runnable) {
+	fmt.Printf("%-20s %-10s %-10s %s\n", "PROJECT", "PRODUCT", "RUNNER", "TAGS")
+	fmt.Println("--------------------------------------------------------")
+
+	for _, r := range runnables {
+		runnerStr := string(r.Runner)
+		if r.Runner == RunnerUnknown {
 			runnerStr = "-"
 		}
-		fmt.Printf("%-20s %-10s %-10s\n", sm.Name, sm.Product, runnerStr)
+		fmt.Printf("%-20s %-10s %-10s %s\n", r.Project, r.Product, runnerStr, strings.Join(r.Tags, ","))
 	}
 }
 
@@ -1,46 +1,242 @@
 package submodule
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/fatih/color"
 	"github.com/inspirai-store/inspirai-devkit/internal/config"
 )
 
-// Status 显示所有 submodule 的状态
-func Status(cfg *config.Config, root string) error {
+// StatusOptions 控制 Status 的行为
+type StatusOptions struct {
+	// Fetch 为 true 时先 fetch 每个仓库，使 ahead/behind 反映远端的最新状态
+	Fetch bool
+	// Short 为 true 时只返回工作区不干净的仓库
+	Short bool
+	// Reporter 接收 --fetch 的进度和错误，为 nil 时不输出
+	Reporter Reporter
+}
+
+// fetchJobs 是 sm status --fetch 并发 fetch 的数量
+const fetchJobs = 4
+
+// RepoStatus 是单个 submodule 的状态
+type RepoStatus struct {
+	Name    string `json:"name" yaml:"name"`
+	Missing bool   `json:"missing,omitempty" yaml:"missing,omitempty"` // 仓库未 clone
+	Branch  string `json:"branch,omitempty" yaml:"branch,omitempty"`   // detached 时为空
+	// Upstream 为空表示没有 upstream，此时 Ahead/Behind 没有意义
+	Upstream string         `json:"upstream,omitempty" yaml:"upstream,omitempty"`
+	Ahead    int            `json:"ahead" yaml:"ahead"`
+	Behind   int            `json:"behind" yaml:"behind"`
+	Worktree WorktreeStatus `json:"worktree" yaml:"worktree"`
+	// Operation 是进行中的 rebase、merge、cherry-pick 或 revert
+	Operation string `json:"operation,omitempty" yaml:"operation,omitempty"`
+	Stashes   int    `json:"stashes" yaml:"stashes"`
+	Commit    string `json:"commit,omitempty" yaml:"commit,omitempty"`   // HEAD 的缩写 SHA
+	Subject   string `json:"subject,omitempty" yaml:"subject,omitempty"` // HEAD 的提交说明
+	// Lock 是相对 sm.lock 的偏移，没有锁文件时为 nil
+	Lock       *Drift `json:"lock,omitempty" yaml:"lock,omitempty"`
+	FetchError string `json:"fetch_error,omitempty" yaml:"fetch_error,omitempty"` // --fetch 失败的原因
+	Error      string `json:"error,omitempty" yaml:"error,omitempty"`             // 读取工作区状态失败的原因
+}
+
+// Clean 判断仓库的工作区是否干净且没有进行中的操作
+func (s RepoStatus) Clean() bool {
+	return !s.Missing && s.Error == "" && s.Operation == "" && s.Worktree.Clean()
+}
+
+// Status 收集所有 submodule 的状态
+//
+// 项目根目录存在 sm.lock 时额外计算每个仓库相对锁定提交的偏移。
+func Status(cfg *config.Config, root string, opts StatusOptions) ([]RepoStatus, error) {
 	submodulesDir := filepath.Join(root, cfg.SubmodulesDir)
 
-	fmt.Printf("%-20s %-15s %-10s %s\n", "NAME", "BRANCH", "STATUS", "COMMIT")
-	fmt.Println(strings.Repeat("-", 70))
+	lock, err := config.LoadLock(root)
+	if errors.Is(err, fs.ErrNotExist) {
+		lock = nil
+	} else if err != nil {
+		return nil, err
+	}
 
-	for _, sm := range cfg.Submodules {
-		smPath := filepath.Join(submodulesDir, sm.Name)
+	var fetchErrors map[string]string
+	if opts.Fetch {
+		fetchErrors = fetchAll(cfg, submodulesDir, orSilent(opts.Reporter))
+	}
 
-		if _, err := os.Stat(smPath); os.IsNotExist(err) {
-			color.Red("%-20s %-15s %-10s %s\n", sm.Name, "-", "missing", "-")
+	statuses := []RepoStatus{}
+	for _, sm := range cfg.Submodules {
+		status := repoStatus(submodulesDir, sm, lock)
+		status.FetchError = fetchErrors[sm.Name]
+		if opts.Short && status.Clean() {
 			continue
 		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func repoStatus(submodulesDir string, sm config.SubmoduleConfig, lock *config.Lock) RepoStatus {
+	status := RepoStatus{Name: sm.Name}
+	if lock != nil {
+		drift := submoduleDrift(submodulesDir, sm, lock)
+		status.Lock = &drift
+	}
+
+	smPath := filepath.Join(submodulesDir, sm.Name)
+	if _, err := os.Stat(smPath); os.IsNotExist(err) {
+		status.Missing = true
+		return status
+	}
+
+	worktree, err := getGitStatus(smPath)
+	if err != nil {
+		status.Error = err.Error()
+	}
+	status.Worktree = worktree
+	status.Operation = inProgress(smPath)
+	status.Branch = getGitBranch(smPath)
+	status.Upstream, status.Ahead, status.Behind, _ = getGitUpstream(smPath)
+	status.Stashes = getGitStashCount(smPath)
+	status.Commit, status.Subject = getGitCommit(smPath)
+	return status
+}
+
+// PrintStatus 以表格打印 Status 的结果；long 为 true 时在每个仓库下列出有变化的文件
+func PrintStatus(statuses []RepoStatus, long bool) {
+	locked := slices.ContainsFunc(statuses, func(s RepoStatus) bool { return s.Lock != nil })
 
-		branch := getGitBranch(smPath)
-		status := getGitStatus(smPath)
-		commit := getGitCommit(smPath)
+	header := fmt.Sprintf("%-20s %-15s %-20s %-8s %-14s %-6s ", "NAME", "BRANCH", "UPSTREAM", "SYNC", "STATUS", "STASH")
+	width := 110
+	if locked {
+		header += fmt.Sprintf("%-18s ", "LOCK")
+		width += 19
+	}
+	fmt.Println(header + "COMMIT")
+	fmt.Println(strings.Repeat("-", width))
 
-		statusColor := color.New(color.FgGreen)
-		if status != "clean" {
+	for _, s := range statuses {
+		if s.Missing {
+			line := fmt.Sprintf("%-20s %-15s %-20s %-8s %-14s %-6s ", s.Name, "-", "-", "-", "missing", "-")
+			if locked {
+				line += fmt.Sprintf("%-18s ", "-")
+			}
+			color.Red("%s-", line)
+			continue
+		}
+
+		status, statusColor := s.Worktree.Short(), color.New(color.FgGreen)
+		switch {
+		case s.Operation != "":
+			status, statusColor = operationLabels[s.Operation], color.New(color.FgRed)
+		case s.Error != "":
+			status, statusColor = "error", color.New(color.FgRed)
+		case s.Worktree.Conflicted > 0:
+			statusColor = color.New(color.FgRed)
+		case !s.Worktree.Clean():
 			statusColor = color.New(color.FgYellow)
 		}
 
-		fmt.Printf("%-20s %-15s ", sm.Name, branch)
-		statusColor.Printf("%-10s ", status)
+		upstream, syncLabel, syncColor := "-", "-", color.New(color.FgYellow)
+		if s.Upstream != "" {
+			upstream = s.Upstream
+			syncLabel = fmt.Sprintf("+%d -%d", s.Ahead, s.Behind)
+			if s.Ahead == 0 && s.Behind == 0 {
+				syncColor = color.New(color.FgGreen)
+			}
+		}
+
+		stash := "-"
+		if s.Stashes > 0 {
+			stash = fmt.Sprint(s.Stashes)
+		}
+
+		commit := strings.TrimSpace(s.Commit + " " + s.Subject)
+		if len(commit) > 50 {
+			commit = commit[:47] + "..."
+		}
+
+		fmt.Printf("%-20s %-15s %-20s ", s.Name, s.Branch, upstream)
+		syncColor.Printf("%-8s ", syncLabel)
+		statusColor.Printf("%-14s ", status)
+		fmt.Printf("%-6s ", stash)
+		if locked {
+			driftColor := color.New(color.FgGreen)
+			if s.Lock == nil || s.Lock.Kind != DriftLocked {
+				driftColor = color.New(color.FgYellow)
+			}
+			label := "-"
+			if s.Lock != nil {
+				label = s.Lock.String()
+			}
+			driftColor.Printf("%-18s ", label)
+		}
 		fmt.Printf("%s\n", commit)
+
+		if long {
+			printChangedFiles(s.Worktree)
+		}
 	}
+}
 
-	return nil
+// printChangedFiles 以 git status --short 的格式列出仓库中有变化的文件
+func printChangedFiles(worktree WorktreeStatus) {
+	for _, f := range worktree.Files {
+		c := color.New(color.FgYellow)
+		switch {
+		case f.Code == "??":
+			c = color.New(color.FgHiBlack)
+		case strings.Contains(f.Code, "U") || f.Code == "AA" || f.Code == "DD":
+			c = color.New(color.FgRed)
+		case f.Code[1] == ' ':
+			c = color.New(color.FgGreen)
+		}
+		path := f.Path
+		if f.OrigPath != "" {
+			path = f.OrigPath + " -> " + f.Path
+		}
+		c.Printf("    %s %s\n", f.Code, path)
+	}
+	if !worktree.Clean() {
+		fmt.Println()
+	}
+}
+
+// operationLabels 是进行中的操作在 STATUS 列中的显示
+var operationLabels = map[string]string{
+	"rebase":      "rebasing",
+	"merge":       "merging",
+	"cherry-pick": "cherry-picking",
+	"revert":      "reverting",
+}
+
+// fetchAll 并发 fetch 所有已 clone 的仓库，返回失败仓库的错误
+func fetchAll(cfg *config.Config, submodulesDir string, r Reporter) map[string]string {
+	result := runPool(cfg.Submodules, fetchJobs, false, "fetched", func(sm config.SubmoduleConfig) RepoResult {
+		smPath := filepath.Join(submodulesDir, sm.Name)
+		if _, err := os.Stat(smPath); os.IsNotExist(err) {
+			return RepoResult{Name: sm.Name, Outcome: OutcomeSkipped}
+		}
+		r.Progress(sm.Name, "fetching")
+		if err := runQuiet(smPath, "fetch", "--quiet"); err != nil {
+			r.Error(sm.Name, err)
+			return RepoResult{Name: sm.Name, Outcome: OutcomeFailed, Detail: err.Error(), Err: err}
+		}
+		return RepoResult{Name: sm.Name, Outcome: OutcomeDone}
+	})
+
+	errs := map[string]string{}
+	for _, repo := range result.Failed() {
+		errs[repo.Name] = repo.Detail
+	}
+	return errs
 }
 
 func getGitBranch(path string) string {
@@ -52,27 +248,155 @@ func getGitBranch(path string) string {
 	return strings.TrimSpace(string(out))
 }
 
-func getGitStatus(path string) string {
-	cmd := exec.Command("git", "-C", path, "status", "--porcelain")
+// WorktreeStatus 是 git status --porcelain=v2 按类别统计的工作区状态
+type WorktreeStatus struct {
+	Staged     int          `json:"staged" yaml:"staged"`     // 已暂存的修改
+	Unstaged   int          `json:"unstaged" yaml:"unstaged"` // 未暂存的修改
+	Untracked  int          `json:"untracked" yaml:"untracked"`
+	Conflicted int          `json:"conflicted" yaml:"conflicted"`
+	Files      []FileChange `json:"files,omitempty" yaml:"files,omitempty"`
+}
+
+// FileChange 是工作区中一个有变化的文件
+type FileChange struct {
+	// Code 是 git status --short 风格的两位状态码，如 "M "、" M"、"??"、"UU"
+	Code     string `json:"code" yaml:"code"`
+	Path     string `json:"path" yaml:"path"`
+	OrigPath string `json:"orig_path,omitempty" yaml:"orig_path,omitempty"` // 重命名或复制前的路径
+}
+
+// Clean 判断工作区是否没有任何变化
+func (s WorktreeStatus) Clean() bool {
+	return len(s.Files) == 0
+}
+
+// String 返回可读的描述，如 "2 staged, 1 modified"
+func (s WorktreeStatus) String() string {
+	if s.Clean() {
+		return "clean"
+	}
+	var parts []string
+	for _, c := range []struct {
+		n    int
+		name string
+	}{
+		{s.Conflicted, "conflicted"},
+		{s.Staged, "staged"},
+		{s.Unstaged, "modified"},
+		{s.Untracked, "untracked"},
+	} {
+		if c.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", c.n, c.name))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Short 返回状态表中使用的紧凑形式：=冲突 +已暂存 !未暂存 ?未跟踪
+func (s WorktreeStatus) Short() string {
+	if s.Clean() {
+		return "clean"
+	}
+	var parts []string
+	for _, c := range []struct {
+		n    int
+		mark string
+	}{
+		{s.Conflicted, "="},
+		{s.Staged, "+"},
+		{s.Unstaged, "!"},
+		{s.Untracked, "?"},
+	} {
+		if c.n > 0 {
+			parts = append(parts, fmt.Sprintf("%s%d", c.mark, c.n))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// getGitStatus 解析 git status --porcelain=v2 的输出
+func getGitStatus(path string) (WorktreeStatus, error) {
+	var status WorktreeStatus
+
+	cmd := exec.Command("git", "-C", path, "status", "--porcelain=v2", "-z")
 	out, err := cmd.Output()
 	if err != nil {
-		return "error"
+		return status, fmt.Errorf("git status: %w", err)
 	}
-	if len(strings.TrimSpace(string(out))) > 0 {
-		return "modified"
+
+	// -z 时每条记录以 NUL 结尾，重命名记录后面额外跟一个原路径
+	records := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	for i := 0; i < len(records); i++ {
+		record := records[i]
+		if record == "" {
+			continue
+		}
+		switch record[0] {
+		case '1', '2', 'u':
+			// 普通: 1 XY sub mH mI mW hH hI path
+			// 重命名: 2 XY sub mH mI mW hH hI Xscore path
+			// 冲突: u XY sub m1 m2 m3 mW h1 h2 h3 path
+			fields := map[byte]int{'1': 9, '2': 10, 'u': 11}[record[0]]
+			parts := strings.SplitN(record, " ", fields)
+			if len(parts) < fields {
+				continue
+			}
+			xy := parts[1]
+			change := FileChange{Code: strings.ReplaceAll(xy, ".", " "), Path: parts[fields-1]}
+			if record[0] == '2' && i+1 < len(records) {
+				i++
+				change.OrigPath = records[i]
+			}
+
+			if record[0] == 'u' {
+				status.Conflicted++
+			} else {
+				if xy[0] != '.' {
+					status.Staged++
+				}
+				if xy[1] != '.' {
+					status.Unstaged++
+				}
+			}
+			status.Files = append(status.Files, change)
+		case '?':
+			status.Untracked++
+			status.Files = append(status.Files, FileChange{Code: "??", Path: record[2:]})
+		}
 	}
-	return "clean"
+	return status, nil
 }
 
-func getGitCommit(path string) string {
+// getGitCommit 返回 HEAD 的缩写 SHA 和提交说明
+func getGitCommit(path string) (sha, subject string) {
 	cmd := exec.Command("git", "-C", path, "log", "-1", "--format=%h %s")
 	out, err := cmd.Output()
 	if err != nil {
-		return "unknown"
+		return "unknown", ""
+	}
+	sha, subject, _ = strings.Cut(strings.TrimSpace(string(out)), " ")
+	return sha, subject
+}
+
+// getGitUpstream 返回当前分支的 upstream 以及相对它的 ahead/behind 提交数，
+// 没有 upstream 时 ok 为 false
+func getGitUpstream(path string) (upstream string, ahead, behind int, ok bool) {
+	upstream, err := gitOutput(path, "rev-parse", "--abbrev-ref", "@{upstream}")
+	if err != nil {
+		return "", 0, 0, false
+	}
+	out, err := gitOutput(path, "rev-list", "--left-right", "--count", "HEAD...@{upstream}")
+	if err != nil {
+		return upstream, 0, 0, false
 	}
-	result := strings.TrimSpace(string(out))
-	if len(result) > 50 {
-		result = result[:47] + "..."
+	fmt.Sscan(out, &ahead, &behind)
+	return upstream, ahead, behind, true
+}
+
+func getGitStashCount(path string) int {
+	out, err := gitOutput(path, "stash", "list")
+	if err != nil || out == "" {
+		return 0
 	}
-	return result
+	return len(strings.Split(out, "\n"))
 }
@@ -0,0 +1,58 @@
+package submodule
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestGetGitStatus(t *testing.T) {
+	isolateGit(t)
+	repo := t.TempDir()
+	git(t, repo, "init", "-q", "-b", "main")
+	for _, name := range []string{"staged.txt", "unstaged.txt", "old name.txt", "conflict.txt"} {
+		writeFile(t, repo, name, name+"\n")
+	}
+	git(t, repo, "add", ".")
+	git(t, repo, "commit", "-q", "-m", "initial")
+
+	// 两个分支修改同一文件，合并时产生冲突
+	git(t, repo, "checkout", "-q", "-b", "other")
+	commitFile(t, repo, "conflict.txt", "other\n")
+	git(t, repo, "checkout", "-q", "main")
+	commitFile(t, repo, "conflict.txt", "main\n")
+	if err := exec.Command("git", "-C", repo, "merge", "-q", "other").Run(); err == nil {
+		t.Fatal("merge succeeded, want a conflict")
+	}
+
+	writeFile(t, repo, "staged.txt", "staged\n")
+	git(t, repo, "add", "staged.txt")
+	writeFile(t, repo, "staged.txt", "staged and changed\n")
+	writeFile(t, repo, "unstaged.txt", "changed\n")
+	git(t, repo, "mv", "old name.txt", "new name.txt")
+	writeFile(t, repo, "untracked file.txt", "new\n")
+
+	status, err := getGitStatus(repo)
+	if err != nil {
+		t.Fatalf("getGitStatus: %v", err)
+	}
+	if status.Staged != 2 || status.Unstaged != 2 || status.Untracked != 1 || status.Conflicted != 1 {
+		t.Errorf("counts = staged %d, unstaged %d, untracked %d, conflicted %d, want 2, 2, 1, 1",
+			status.Staged, status.Unstaged, status.Untracked, status.Conflicted)
+	}
+
+	want := map[string]FileChange{
+		"staged.txt":         {Code: "MM", Path: "staged.txt"},
+		"unstaged.txt":       {Code: " M", Path: "unstaged.txt"},
+		"new name.txt":       {Code: "R ", Path: "new name.txt", OrigPath: "old name.txt"},
+		"conflict.txt":       {Code: "UU", Path: "conflict.txt"},
+		"untracked file.txt": {Code: "??", Path: "untracked file.txt"},
+	}
+	if len(status.Files) != len(want) {
+		t.Fatalf("Files = %+v, want %d entries", status.Files, len(want))
+	}
+	for _, f := range status.Files {
+		if f != want[f.Path] {
+			t.Errorf("%s: %+v, want %+v", f.Path, f, want[f.Path])
+		}
+	}
+}
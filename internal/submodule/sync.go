@@ -1,38 +1,191 @@
 package submodule
 
 import (
+	"bytes"
+	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
+	"sync"
 
-	"github.com/fatih/color"
 	"github.com/inspirai-store/inspirai-devkit/internal/config"
 )
 
-// Sync 同步所有 submodule
-func Sync(cfg *config.Config, root string) error {
+// SyncOptions 控制 Sync 的行为
+type SyncOptions struct {
+	// FailFast 为 true 时遇到第一个失败就停止
+	FailFast bool
+	// UpdatePins 为 true 时把固定了 tag/revision 的仓库重新检出到 manifest 中的引用，
+	// 否则跳过这些仓库
+	UpdatePins bool
+	// Strategy 不为空时覆盖每个 submodule 在 manifest 中配置的同步方式
+	Strategy string
+	// Autostash 为 true 时在 pull 前后自动 stash 本地修改
+	Autostash bool
+	// Force 为 true 时不检查本地修改、detached HEAD 和 upstream，直接同步
+	Force bool
+	// Jobs 是并发同步的数量，小于等于 1 时串行执行
+	Jobs int
+	// Reporter 接收每个仓库的进度，为 nil 时不输出
+	Reporter Reporter
+}
+
+// DefaultStrategy 是 manifest 和命令行都没有指定时的同步方式
+const DefaultStrategy = "rebase"
+
+// Sync 同步所有 submodule，返回每个仓库的结果和拉取到的新提交
+func Sync(cfg *config.Config, root string, opts SyncOptions) *SyncReport {
 	submodulesDir := filepath.Join(root, cfg.SubmodulesDir)
+	r := orSilent(opts.Reporter)
+
+	var mu sync.Mutex
+	changes := map[string]SyncChange{}
+	result := runPool(cfg.Submodules, opts.Jobs, opts.FailFast, "synced", func(sm config.SubmoduleConfig) RepoResult {
+		r.Start(sm.Name)
+		var out bytes.Buffer
+		repo, change := syncSubmodule(filepath.Join(submodulesDir, sm.Name), sm, opts, &out, func(phase string) {
+			r.Progress(sm.Name, phase)
+		})
+		r.Finish(repo, out.Bytes())
+
+		mu.Lock()
+		changes[sm.Name] = change
+		mu.Unlock()
+		return repo
+	})
+
+	return newSyncReport(result, changes)
+}
+
+// syncStep 是同步过程中的一步 git 操作
+type syncStep struct {
+	phase string // 显示在进度中的阶段名
+	args  []string
+}
 
-	for _, sm := range cfg.Submodules {
-		smPath := filepath.Join(submodulesDir, sm.Name)
+// syncSubmodule 同步单个仓库，git 输出写入 out，每进入一个阶段调用一次 phase；
+// 返回结果和同步前后 HEAD 之间的变更
+func syncSubmodule(smPath string, sm config.SubmoduleConfig, opts SyncOptions, out io.Writer, phase func(string)) (RepoResult, SyncChange) {
+	var change SyncChange
+	if _, err := os.Stat(smPath); os.IsNotExist(err) {
+		return RepoResult{Name: sm.Name, Outcome: OutcomeSkipped, Detail: "not found"}, change
+	}
 
-		if _, err := os.Stat(smPath); os.IsNotExist(err) {
-			color.Yellow("  [skip] %s not found", sm.Name)
-			continue
+	pin := sm.Pin()
+	if pin != "" && !opts.UpdatePins {
+		return RepoResult{Name: sm.Name, Outcome: OutcomeSkipped, Detail: "pinned to " + pin + " (use --update-pins)"}, change
+	}
+	strategy := syncStrategy(sm, opts)
+	if reason := syncBlocker(smPath, strategy, pin != "", opts); reason != "" {
+		return RepoResult{Name: sm.Name, Outcome: OutcomeSkipped, Detail: reason}, change
+	}
+
+	var steps []syncStep
+	if pin != "" {
+		steps = []syncStep{{"fetching", []string{"fetch", "--tags", "origin"}}}
+		if sm.Revision != "" && !hasCommit(smPath, sm.Revision) {
+			// 浅克隆或单分支时 revision 可能不在 fetch 到的历史中，和 init 一样单独获取
+			steps = append(steps, syncStep{"fetching " + sm.Revision, []string{"fetch", "origin", sm.Revision}})
 		}
+		steps = append(steps, syncStep{"checking out " + pin, []string{"-c", "advice.detachedHead=false", "checkout", "--detach", pin}})
+	} else {
+		steps = strategySteps(strategy, opts.Autostash)
+	}
 
-		color.Cyan("  [sync] %s", sm.Name)
+	change.Before, _ = gitOutput(smPath, "rev-parse", "HEAD")
 
-		cmd := exec.Command("git", "-C", smPath, "pull", "--rebase")
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+	for _, step := range steps {
+		phase(step.phase)
+		cmd := exec.Command("git", append([]string{"-C", smPath}, step.args...)...)
+		cmd.Stdout = out
+		cmd.Stderr = out
 		if err := cmd.Run(); err != nil {
-			color.Red("  [error] %s: %v", sm.Name, err)
-			continue
+			if op := abortInProgress(smPath); op != "" {
+				err = fmt.Errorf("%s stopped with conflicts and was aborted", op)
+				return RepoResult{Name: sm.Name, Outcome: OutcomeConflicted, Detail: err.Error(), Err: err}, change
+			}
+			err = fmt.Errorf("git %s: %w", strings.Join(step.args, " "), err)
+			return RepoResult{Name: sm.Name, Outcome: OutcomeFailed, Detail: err.Error(), Err: err}, change
 		}
+	}
+
+	recordChange(smPath, &change)
+	return RepoResult{Name: sm.Name, Outcome: OutcomeDone}, change
+}
 
-		color.Green("  [done] %s", sm.Name)
+// syncStrategy 按命令行 > manifest > 默认值的顺序决定同步方式
+func syncStrategy(sm config.SubmoduleConfig, opts SyncOptions) string {
+	switch {
+	case opts.Strategy != "":
+		return opts.Strategy
+	case sm.Strategy != "":
+		return sm.Strategy
 	}
+	return DefaultStrategy
+}
 
-	return nil
+// syncBlocker 检查仓库当前是否适合同步，不适合时返回原因
+//
+// 进行中的 rebase/merge 即使 Force 也会跳过：同步失败后的自动 abort
+// 会连同用户自己的操作一起撤销。pinned 为 true 时仓库会被检出为 detached HEAD，
+// 不检查分支和 upstream。
+func syncBlocker(path, strategy string, pinned bool, opts SyncOptions) string {
+	if op := inProgress(path); op != "" {
+		return op + " in progress"
+	}
+	if opts.Force || (!pinned && strategy == "fetch") {
+		return ""
+	}
+
+	if !opts.Autostash {
+		if status, err := getGitStatus(path); err != nil || !status.Clean() {
+			return "uncommitted changes (commit, stash or use --autostash)"
+		}
+	}
+	if pinned {
+		return ""
+	}
+	if branch, _ := gitOutput(path, "branch", "--show-current"); branch == "" {
+		return "detached HEAD"
+	}
+	if _, err := gitOutput(path, "rev-parse", "--abbrev-ref", "@{upstream}"); err != nil {
+		return "no upstream branch"
+	}
+	return ""
+}
+
+// strategySteps 把同步方式转换为 git 操作：先 fetch，再按方式整合 upstream
+func strategySteps(strategy string, autostash bool) []syncStep {
+	fetch := syncStep{"fetching", []string{"fetch", "--prune"}}
+
+	var integrate syncStep
+	switch strategy {
+	case "fetch":
+		return []syncStep{fetch}
+	case "merge":
+		integrate = syncStep{"merging", []string{"merge", "--no-edit", "@{upstream}"}}
+	case "ff-only":
+		integrate = syncStep{"fast-forwarding", []string{"merge", "--ff-only", "@{upstream}"}}
+	default:
+		integrate = syncStep{"rebasing", []string{"rebase", "@{upstream}"}}
+	}
+	if autostash {
+		integrate.args = append(integrate.args, "--autostash")
+	}
+	return []syncStep{fetch, integrate}
+}
+
+// abortInProgress 中止因冲突停下的 rebase 或 merge，返回被中止的操作名
+func abortInProgress(path string) string {
+	op := inProgress(path)
+	switch op {
+	case "rebase", "merge":
+		if err := runQuiet(path, op, "--abort"); err != nil {
+			return ""
+		}
+		return op
+	}
+	return ""
 }
@@ -0,0 +1,118 @@
+package submodule
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/inspirai-store/inspirai-devkit/internal/config"
+)
+
+func TestSyncUpdatePinsChecksWorktree(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, smPath, work string)
+		opts    SyncOptions
+		outcome Outcome
+		detail  string
+	}{
+		{
+			name: "rebase in progress is kept",
+			setup: func(t *testing.T, smPath, work string) {
+				commitFile(t, work, "README", "origin\n")
+				git(t, work, "push", "-q", "origin", "HEAD:main")
+				commitFile(t, smPath, "README", "local\n")
+				git(t, smPath, "fetch", "-q")
+				// 制造冲突，使 rebase 停在中途
+				exec.Command("git", "-C", smPath, "rebase", "origin/main").Run()
+			},
+			opts:    SyncOptions{UpdatePins: true, Force: true},
+			outcome: OutcomeSkipped,
+			detail:  "rebase in progress",
+		},
+		{
+			name: "uncommitted changes",
+			setup: func(t *testing.T, smPath, work string) {
+				writeFile(t, smPath, "README", "dirty\n")
+			},
+			opts:    SyncOptions{UpdatePins: true},
+			outcome: OutcomeSkipped,
+			detail:  "uncommitted changes (commit, stash or use --autostash)",
+		},
+		{
+			name: "untracked files with --force",
+			setup: func(t *testing.T, smPath, work string) {
+				writeFile(t, smPath, "notes.txt", "mine\n")
+			},
+			opts:    SyncOptions{UpdatePins: true, Force: true},
+			outcome: OutcomeDone,
+		},
+		{
+			name:    "pins are skipped without --update-pins",
+			setup:   func(t *testing.T, smPath, work string) {},
+			outcome: OutcomeSkipped,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolateGit(t)
+			bare, work := newOrigin(t)
+			pin := git(t, work, "rev-parse", "HEAD")
+			commitFile(t, work, "CHANGELOG", "two\n")
+			git(t, work, "push", "-q", "origin", "HEAD:main")
+
+			root := t.TempDir()
+			smPath := filepath.Join(root, ".submodules", "alpha")
+			git(t, root, "clone", "-q", bare, smPath)
+			tt.setup(t, smPath, work)
+			before := inProgress(smPath)
+
+			cfg := &config.Config{
+				SubmodulesDir: ".submodules",
+				Submodules:    []config.SubmoduleConfig{{Name: "alpha", Repo: bare, Revision: pin}},
+			}
+			report := Sync(cfg, root, tt.opts)
+
+			got := report.Repos[0]
+			if got.Outcome != tt.outcome || (tt.detail != "" && got.Detail != tt.detail) {
+				t.Fatalf("outcome = %s (%s), want %s (%s)", got.Outcome, got.Detail, tt.outcome, tt.detail)
+			}
+			if after := inProgress(smPath); after != before {
+				t.Errorf("operation in progress changed from %q to %q", before, after)
+			}
+			head := git(t, smPath, "rev-parse", "HEAD")
+			if tt.outcome == OutcomeDone && head != pin {
+				t.Errorf("HEAD = %s, want pin %s", head, pin)
+			}
+			if tt.outcome != OutcomeDone && head == pin {
+				t.Errorf("HEAD was moved to the pin although the repo was skipped")
+			}
+		})
+	}
+}
+
+func TestSyncUpdatePinsFetchesRevision(t *testing.T) {
+	isolateGit(t)
+	bare, work := newOrigin(t)
+	// 固定的提交只在 side 分支上，单分支 clone 不会获取它
+	git(t, work, "checkout", "-q", "-b", "side")
+	pin := commitFile(t, work, "SIDE", "side\n")
+	git(t, work, "push", "-q", "origin", "side")
+
+	root := t.TempDir()
+	smPath := filepath.Join(root, ".submodules", "alpha")
+	git(t, root, "clone", "-q", "--single-branch", "--branch", "main", "file://"+bare, smPath)
+
+	cfg := &config.Config{
+		SubmodulesDir: ".submodules",
+		Submodules:    []config.SubmoduleConfig{{Name: "alpha", Repo: bare, Revision: pin, SingleBranch: true}},
+	}
+	report := Sync(cfg, root, SyncOptions{UpdatePins: true})
+	if got := report.Repos[0]; got.Outcome != OutcomeDone {
+		t.Fatalf("outcome = %s (%s), want done", got.Outcome, got.Detail)
+	}
+	if head := git(t, smPath, "rev-parse", "HEAD"); head != pin {
+		t.Errorf("HEAD = %s, want pin %s", head, pin)
+	}
+}
@@ -0,0 +1,86 @@
+package submodule
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/inspirai-store/inspirai-devkit/internal/config"
+)
+
+// UnshallowOptions 控制 Unshallow 的行为
+type UnshallowOptions struct {
+	// Deepen 大于 0 时只向前加深指定数量的提交，否则获取完整历史
+	Deepen int
+	// AllBranches 为 true 时取消 single_branch 限制，获取所有远程分支
+	AllBranches bool
+	// NoSparse 为 true 时关闭 sparse-checkout，检出全部文件
+	NoSparse bool
+	// Reporter 接收执行的 git 命令和结果，为 nil 时不输出
+	Reporter Reporter
+}
+
+// Unshallow 补全以 depth、single_branch 或 sparse 方式 clone 的仓库，返回该仓库的结果
+func Unshallow(cfg *config.Config, root string, name string, opts UnshallowOptions) (*Result, error) {
+	smPath := filepath.Join(root, cfg.SubmodulesDir, name)
+	if _, err := os.Stat(smPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("submodule '%s' not found in %s", name, filepath.Join(root, cfg.SubmodulesDir))
+	}
+
+	var steps [][]string
+	if opts.AllBranches {
+		steps = append(steps, []string{"remote", "set-branches", "origin", "*"})
+	}
+
+	switch {
+	case opts.Deepen > 0:
+		steps = append(steps, []string{"fetch", "--deepen", strconv.Itoa(opts.Deepen)})
+	case isShallow(smPath):
+		steps = append(steps, []string{"fetch", "--unshallow"})
+	case opts.AllBranches:
+		steps = append(steps, []string{"fetch"})
+	}
+
+	if opts.NoSparse {
+		steps = append(steps, []string{"sparse-checkout", "disable"})
+	}
+
+	r := orSilent(opts.Reporter)
+	r.Start(name)
+	result := NewResult("unshallowed")
+	if len(steps) == 0 {
+		result.Skip(name, "already has its full history")
+		r.Finish(result.Repos[0], nil)
+		return result, nil
+	}
+
+	var out bytes.Buffer
+	for _, args := range steps {
+		r.Progress(name, "git "+strings.Join(args, " "))
+		cmd := exec.Command("git", append([]string{"-C", smPath}, args...)...)
+		cmd.Stdout = &out
+		cmd.Stderr = &out
+		if err := cmd.Run(); err != nil {
+			result.Fail(name, fmt.Errorf("git %s: %w", args[0], err))
+			r.Finish(result.Repos[0], out.Bytes())
+			return result, nil
+		}
+	}
+
+	result.Done(name)
+	r.Finish(result.Repos[0], nil)
+	return result, nil
+}
+
+func isShallow(path string) bool {
+	cmd := exec.Command("git", "-C", path, "rev-parse", "--is-shallow-repository")
+	out, err := cmd.Output()
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(out)) == "true"
+}